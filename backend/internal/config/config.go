@@ -3,20 +3,59 @@ package config
 import (
 	"log"
 	"os"
+	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
 
 type Config struct {
-	Port        string
-	DatabaseURL string
-	JWTSecret   string
-	DOKey       string
-	DOSecret    string
-	DOEndpoint  string
-	DORegion    string
-	DOBucket    string
+	Port                string
+	DatabaseURL         string
+	JWTSecret           string
+	DOKey               string
+	DOSecret            string
+	DOEndpoint          string
+	DORegion            string
+	DOBucket            string
 	GuestExpirationDays int
+
+	// Public URL of the frontend, used to build links sent by email
+	AppURL string
+
+	// Base of the /v/<code> share links encoded in QR codes, e.g. a short
+	// domain. Defaults to AppURL.
+	ShareBaseURL string
+
+	// Outgoing mail (magic links). Leave SMTP_HOST empty to log mail instead.
+	SMTPHost     string
+	SMTPPort     string
+	SMTPUsername string
+	SMTPPassword string
+	SMTPFrom     string
+
+	MagicLinkTTLMinutes int
+	MagicLinkMaxPerHour int
+
+	// Optional HttpOnly cookie sessions (with double-submit CSRF protection)
+	CookieAuth     bool
+	CookieDomain   string
+	CookieSecure   bool
+	CookieSameSite string
+
+	// Allowed CORS origins. Empty allows any origin, without credentials.
+	CORSAllowedOrigins []string
+
+	// Proxies whose X-Forwarded-For is believed when working out client IPs
+	TrustedProxies []string
+
+	// Argon2id password hashing parameters
+	Argon2MemoryKB    int
+	Argon2Iterations  int
+	Argon2Parallelism int
+
+	// Days a deleted strip or user stays in the trash before it is purged
+	TrashRetentionDays int
 }
 
 func LoadConfig() *Config {
@@ -24,16 +63,54 @@ func LoadConfig() *Config {
 		log.Println("No .env file found, using system environment variables")
 	}
 
+	appURL := getEnv("APP_URL", "http://localhost:8080")
+	shareBaseURL := os.Getenv("SHARE_BASE_URL")
+	if shareBaseURL == "" {
+		shareBaseURL = appURL
+	}
+
+	// Defaults to private networks, where nginx runs next to the backend
+	trustedProxies := getEnvList("TRUSTED_PROXIES")
+	if len(trustedProxies) == 0 {
+		trustedProxies = []string{"127.0.0.1/8", "10.0.0.0/8", "172.16.0.0/12", "192.168.0.0/16", "::1/128", "fc00::/7"}
+	}
+
 	return &Config{
-		Port:        getEnv("PORT", "3000"),
-		DatabaseURL: os.Getenv("DATABASE_URL"),
-		JWTSecret:   os.Getenv("JWT_SECRET"),
-		DOKey:       os.Getenv("DO_SPACES_KEY"),
-		DOSecret:    os.Getenv("DO_SPACES_SECRET"),
-		DOEndpoint:  os.Getenv("DO_SPACES_ENDPOINT"),
-		DORegion:    os.Getenv("DO_SPACES_REGION"),
-		DOBucket:    os.Getenv("DO_SPACES_BUCKET"),
+		Port:                getEnv("PORT", "3000"),
+		DatabaseURL:         os.Getenv("DATABASE_URL"),
+		JWTSecret:           os.Getenv("JWT_SECRET"),
+		DOKey:               os.Getenv("DO_SPACES_KEY"),
+		DOSecret:            os.Getenv("DO_SPACES_SECRET"),
+		DOEndpoint:          os.Getenv("DO_SPACES_ENDPOINT"),
+		DORegion:            os.Getenv("DO_SPACES_REGION"),
+		DOBucket:            os.Getenv("DO_SPACES_BUCKET"),
 		GuestExpirationDays: 7, // Default to 7 days
+
+		AppURL:       appURL,
+		ShareBaseURL: shareBaseURL,
+
+		SMTPHost:     os.Getenv("SMTP_HOST"),
+		SMTPPort:     getEnv("SMTP_PORT", "587"),
+		SMTPUsername: os.Getenv("SMTP_USERNAME"),
+		SMTPPassword: os.Getenv("SMTP_PASSWORD"),
+		SMTPFrom:     getEnv("SMTP_FROM", "no-reply@wuby.app"),
+
+		MagicLinkTTLMinutes: getEnvInt("MAGIC_LINK_TTL_MINUTES", 15),
+		MagicLinkMaxPerHour: getEnvInt("MAGIC_LINK_MAX_PER_HOUR", 5),
+
+		CookieAuth:     getEnvBool("AUTH_COOKIE_MODE", false),
+		CookieDomain:   os.Getenv("AUTH_COOKIE_DOMAIN"),
+		CookieSecure:   getEnvBool("AUTH_COOKIE_SECURE", true),
+		CookieSameSite: getEnv("AUTH_COOKIE_SAMESITE", "lax"),
+
+		CORSAllowedOrigins: getEnvList("CORS_ALLOWED_ORIGINS"),
+		TrustedProxies:     trustedProxies,
+
+		Argon2MemoryKB:    getEnvInt("ARGON2_MEMORY_KB", 64*1024),
+		Argon2Iterations:  getEnvInt("ARGON2_ITERATIONS", 3),
+		Argon2Parallelism: getEnvInt("ARGON2_PARALLELISM", 2),
+
+		TrashRetentionDays: getEnvInt("TRASH_RETENTION_DAYS", 30),
 	}
 }
 
@@ -43,3 +120,39 @@ func getEnv(key, fallback string) string {
 	}
 	return fallback
 }
+
+func getEnvInt(key string, fallback int) int {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	n, err := strconv.Atoi(value)
+	if err != nil {
+		log.Printf("Invalid integer for %s=%q, using default %d", key, value, fallback)
+		return fallback
+	}
+	return n
+}
+
+func getEnvBool(key string, fallback bool) bool {
+	value, ok := os.LookupEnv(key)
+	if !ok || value == "" {
+		return fallback
+	}
+	b, err := strconv.ParseBool(value)
+	if err != nil {
+		log.Printf("Invalid boolean for %s=%q, using default %t", key, value, fallback)
+		return fallback
+	}
+	return b
+}
+
+func getEnvList(key string) []string {
+	var list []string
+	for _, item := range strings.Split(os.Getenv(key), ",") {
+		if item = strings.TrimSpace(item); item != "" {
+			list = append(list, item)
+		}
+	}
+	return list
+}
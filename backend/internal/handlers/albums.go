@@ -0,0 +1,441 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/tokens"
+)
+
+const (
+	albumShareSlugLength = 10
+	maxAlbumNameLength   = 120
+)
+
+var errAlbumStripsMismatch = errors.New("strip_ids must list every strip in the album exactly once")
+
+// albumSummary is an album plus the number of strips in it, as returned by
+// ListAlbums.
+type albumSummary struct {
+	models.Album
+	StripCount int64 `json:"strip_count"`
+}
+
+func (h *Handler) ListAlbums(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var albums []albumSummary
+	if err := h.DB.Model(&models.Album{}).
+		Select("albums.*, (SELECT COUNT(*) FROM album_strips JOIN strips ON strips.id = album_strips.strip_id "+
+			"WHERE album_strips.album_id = albums.id AND strips.deleted_at IS NULL) AS strip_count").
+		Where("albums.user_id = ?", userID).
+		Order("albums.created_at DESC").
+		Find(&albums).Error; err != nil {
+		log.Printf("ListAlbums DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch albums"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"albums": albums})
+}
+
+func (h *Handler) CreateAlbum(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Name        string `json:"name"`
+		Description string `json:"description"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	name := strings.TrimSpace(req.Name)
+	if name == "" || len(name) > maxAlbumNameLength {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Album name is required (max 120 characters)"})
+		return
+	}
+
+	album := models.Album{
+		ID:          uuid.New().String(),
+		UserID:      userID,
+		Name:        name,
+		Description: req.Description,
+		CreatedAt:   time.Now(),
+		UpdatedAt:   time.Now(),
+	}
+	if err := h.DB.Create(&album).Error; err != nil {
+		log.Printf("CreateAlbum DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create album"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, album)
+}
+
+func (h *Handler) GetAlbum(c *gin.Context) {
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+
+	strips, err := h.albumStrips(album.ID, false)
+	if err != nil {
+		log.Printf("GetAlbum DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"album": album, "strips": strips})
+}
+
+// UpdateAlbum edits an album's details. Only fields present in the body are
+// changed; an empty cover_strip_id clears the cover, and "public" turns the
+// share slug on or off.
+func (h *Handler) UpdateAlbum(c *gin.Context) {
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		Name         *string `json:"name"`
+		Description  *string `json:"description"`
+		CoverStripID *string `json:"cover_strip_id"`
+		Public       *bool   `json:"public"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	updates := map[string]interface{}{"updated_at": time.Now()}
+
+	if req.Name != nil {
+		name := strings.TrimSpace(*req.Name)
+		if name == "" || len(name) > maxAlbumNameLength {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Album name is required (max 120 characters)"})
+			return
+		}
+		updates["name"] = name
+	}
+	if req.Description != nil {
+		updates["description"] = *req.Description
+	}
+
+	if req.CoverStripID != nil {
+		if *req.CoverStripID == "" {
+			updates["cover_strip_id"] = nil
+		} else {
+			// The cover must be one of the album's own strips
+			var count int64
+			if err := h.DB.Model(&models.AlbumStrip{}).
+				Where("album_id = ? AND strip_id = ?", album.ID, *req.CoverStripID).
+				Count(&count).Error; err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+				return
+			}
+			if count == 0 {
+				c.JSON(http.StatusBadRequest, gin.H{"error": "Cover strip must be in the album"})
+				return
+			}
+			updates["cover_strip_id"] = *req.CoverStripID
+		}
+	}
+
+	if req.Public != nil {
+		switch {
+		case *req.Public && album.ShareSlug == nil:
+			slug, err := tokens.Slug(albumShareSlugLength)
+			if err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+				return
+			}
+			updates["share_slug"] = slug
+		case !*req.Public:
+			updates["share_slug"] = nil
+		}
+	}
+
+	if err := h.DB.Model(&album).Updates(updates).Error; err != nil {
+		log.Printf("UpdateAlbum DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update album"})
+		return
+	}
+
+	h.DB.First(&album, "id = ?", album.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Album updated", "album": album})
+}
+
+// DeleteAlbum removes the album only; its strips are left untouched.
+func (h *Handler) DeleteAlbum(c *gin.Context) {
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("album_id = ?", album.ID).Delete(&models.AlbumStrip{}).Error; err != nil {
+			return err
+		}
+		return tx.Delete(&album).Error
+	})
+	if err != nil {
+		log.Printf("DeleteAlbum DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album deleted"})
+}
+
+// AddAlbumStrips appends the caller's strips to the end of the album, in the
+// order given. Strips already in the album are skipped.
+func (h *Handler) AddAlbumStrips(c *gin.Context) {
+	userID := c.GetString("user_id")
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		StripIDs []string `json:"strip_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.StripIDs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "strip_ids required"})
+		return
+	}
+
+	// 1. Only the album owner's own strips can be added
+	var owned int64
+	if err := h.DB.Model(&models.Strip{}).
+		Where("id IN ? AND user_id = ?", req.StripIDs, userID).
+		Count(&owned).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add strips"})
+		return
+	}
+	if int(owned) != len(uniqueStrings(req.StripIDs)) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	// 2. Append after the current last position
+	err := appendAlbumStrips(h.DB, album.ID, uniqueStrings(req.StripIDs))
+	if err != nil {
+		log.Printf("AddAlbumStrips DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to add strips"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strips added"})
+}
+
+// appendAlbumStrips adds strips to the end of an album, skipping any already
+// in it. The album row is locked so two concurrent adds don't hand out the
+// same positions.
+func appendAlbumStrips(db *gorm.DB, albumID string, stripIDs []string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&models.Album{}, "id = ?", albumID).Error; err != nil {
+			return err
+		}
+
+		var last struct{ Max *int }
+		if err := tx.Model(&models.AlbumStrip{}).Select("MAX(position) AS max").
+			Where("album_id = ?", albumID).Scan(&last).Error; err != nil {
+			return err
+		}
+		next := 0
+		if last.Max != nil {
+			next = *last.Max + 1
+		}
+
+		links := make([]models.AlbumStrip, 0, len(stripIDs))
+		for _, id := range stripIDs {
+			links = append(links, models.AlbumStrip{AlbumID: albumID, StripID: id, Position: next, AddedAt: time.Now()})
+			next++
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+			return err
+		}
+		return tx.Model(&models.Album{}).Where("id = ?", albumID).Update("updated_at", time.Now()).Error
+	})
+}
+
+// RemoveAlbumStrip takes a strip out of the album. If it was the cover, the
+// album is left without one.
+func (h *Handler) RemoveAlbumStrip(c *gin.Context) {
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+	stripID := c.Param("stripId")
+
+	var removed int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("album_id = ? AND strip_id = ?", album.ID, stripID).Delete(&models.AlbumStrip{})
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+
+		updates := map[string]interface{}{"updated_at": time.Now()}
+		if album.CoverStripID != nil && *album.CoverStripID == stripID {
+			updates["cover_strip_id"] = nil
+		}
+		return tx.Model(&album).Updates(updates).Error
+	})
+	if err != nil {
+		log.Printf("RemoveAlbumStrip DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove strip"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip is not in this album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strip removed from album"})
+}
+
+// ReorderAlbumStrips sets the album order. The body must list every strip in
+// the album exactly once, so a stale client can't silently drop strips.
+// Trashed strips keep their old position until restored.
+func (h *Handler) ReorderAlbumStrips(c *gin.Context) {
+	album, ok := h.ownedAlbum(c)
+	if !ok {
+		return
+	}
+
+	var req struct {
+		StripIDs []string `json:"strip_ids"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var current []models.AlbumStrip
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Where("album_id = ? AND strip_id IN (SELECT id FROM strips WHERE deleted_at IS NULL)", album.ID).
+			Find(&current).Error; err != nil {
+			return err
+		}
+
+		inAlbum := make(map[string]bool, len(current))
+		for _, link := range current {
+			inAlbum[link.StripID] = true
+		}
+		if len(req.StripIDs) != len(current) || len(uniqueStrings(req.StripIDs)) != len(current) {
+			return errAlbumStripsMismatch
+		}
+		for _, id := range req.StripIDs {
+			if !inAlbum[id] {
+				return errAlbumStripsMismatch
+			}
+		}
+
+		for i, id := range req.StripIDs {
+			if err := tx.Model(&models.AlbumStrip{}).
+				Where("album_id = ? AND strip_id = ?", album.ID, id).
+				Update("position", i).Error; err != nil {
+				return err
+			}
+		}
+		return tx.Model(&album).Update("updated_at", time.Now()).Error
+	})
+	if errors.Is(err, errAlbumStripsMismatch) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("ReorderAlbumStrips DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to reorder album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Album reordered"})
+}
+
+// GetPublicAlbum renders a shared album by its slug, the way GetPublicStrip
+// does for a single strip.
+func (h *Handler) GetPublicAlbum(c *gin.Context) {
+	slug := c.Param("slug")
+
+	// Albums of trashed users are hidden along with their strips
+	var album models.Album
+	if err := h.DB.Joins("JOIN users ON users.id = albums.user_id AND users.deleted_at IS NULL").
+		First(&album, "albums.share_slug = ?", slug).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		return
+	}
+
+	strips, err := h.albumStrips(album.ID, true)
+	if err != nil {
+		log.Printf("GetPublicAlbum DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch album"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{
+		"album": gin.H{
+			"id":             album.ID,
+			"name":           album.Name,
+			"description":    album.Description,
+			"cover_strip_id": album.CoverStripID,
+			"created_at":     album.CreatedAt,
+		},
+		"strips": strips,
+	})
+}
+
+// ownedAlbum loads the :id album if it belongs to the caller, writing a 404
+// otherwise.
+func (h *Handler) ownedAlbum(c *gin.Context) (models.Album, bool) {
+	var album models.Album
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&album).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+		return album, false
+	}
+	return album, true
+}
+
+// albumStrips returns the album's strips in album order. Shared views leave
+// out private strips.
+func (h *Handler) albumStrips(albumID string, shared bool) ([]models.Strip, error) {
+	query := h.DB.Model(&models.Strip{}).
+		Joins("JOIN album_strips ON album_strips.strip_id = strips.id").
+		Where("album_strips.album_id = ?", albumID)
+	if shared {
+		query = query.Where("strips.visibility <> ?", models.VisibilityPrivate)
+	}
+
+	strips := []models.Strip{}
+	err := query.Order("album_strips.position ASC, album_strips.added_at ASC").Find(&strips).Error
+	return strips, err
+}
+
+func uniqueStrings(values []string) []string {
+	seen := make(map[string]bool, len(values))
+	out := make([]string, 0, len(values))
+	for _, v := range values {
+		if !seen[v] {
+			seen[v] = true
+			out = append(out, v)
+		}
+	}
+	return out
+}
@@ -0,0 +1,198 @@
+package handlers
+
+import (
+	"archive/zip"
+	"context"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+)
+
+// At most this many objects are fetched ahead of the zip writer, which also
+// bounds how many images are held in memory.
+const archiveFetchers = 4
+
+// DownloadArchive streams a zip of the caller's strips with a manifest.csv.
+// ?ids=a,b,c picks strips, ?album=<id> takes an album in album order, and
+// neither takes every strip the caller has.
+func (h *Handler) DownloadArchive(c *gin.Context) {
+	userID := c.GetString("user_id")
+	name := "wuby-strips"
+
+	// 1. Work out which strips go in
+	var strips []models.Strip
+	switch {
+	case c.Query("ids") != "":
+		var ids []string
+		for _, id := range uniqueStrings(strings.Split(c.Query("ids"), ",")) {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+		if len(ids) > maxBulkItems {
+			c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ids can name at most %d strips", maxBulkItems)})
+			return
+		}
+		if err := h.DB.Where("id IN ? AND user_id = ?", ids, userID).Order("created_at ASC").Find(&strips).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+			return
+		}
+		if len(strips) != len(ids) {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+			return
+		}
+
+	case c.Query("album") != "":
+		var album models.Album
+		if err := h.DB.Where("id = ? AND user_id = ?", c.Query("album"), userID).First(&album).Error; err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "Album not found"})
+			return
+		}
+		var err error
+		if strips, err = h.albumStrips(album.ID, false); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+			return
+		}
+		if slug := tagSlug(album.Name); slug != "" {
+			name += "-" + slug
+		}
+
+	default:
+		if err := h.DB.Where("user_id = ?", userID).Order("created_at ASC").Find(&strips).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+			return
+		}
+	}
+	if len(strips) == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "No strips to download"})
+		return
+	}
+
+	// 2. From here on the response is streaming; errors can only be logged
+	c.Header("Content-Type", "application/zip")
+	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s-%s.zip"`, name, time.Now().Format("2006-01-02")))
+	c.Status(http.StatusOK)
+
+	if err := h.writeArchive(c, strips); err != nil {
+		log.Printf("DownloadArchive Error: %v", err)
+	}
+}
+
+type fetchedObject struct {
+	data []byte
+	err  error
+}
+
+// writeArchive fetches the strips' images concurrently, at most
+// archiveFetchers ahead, and writes them to the zip in order.
+func (h *Handler) writeArchive(c *gin.Context, strips []models.Strip) error {
+	// Cancelled on return, so the fetchers stop if writing fails
+	ctx, cancel := context.WithCancel(c.Request.Context())
+	defer cancel()
+
+	// 1. Fetch ahead; a slot is freed once the writer has used its object
+	slots := make([]chan fetchedObject, len(strips))
+	for i := range slots {
+		slots[i] = make(chan fetchedObject, 1)
+	}
+	sem := make(chan struct{}, archiveFetchers)
+	go func() {
+		for i, strip := range strips {
+			select {
+			case sem <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			go func(i int, key string) {
+				if key == "" {
+					slots[i] <- fetchedObject{err: errors.New("strip has no image")}
+					return
+				}
+				data, err := h.getObject(ctx, key)
+				slots[i] <- fetchedObject{data: data, err: err}
+			}(i, storageKey(strip.FileURL))
+		}
+	}()
+
+	// 2. Write in order; PNGs are already compressed, so store them as is
+	zw := zip.NewWriter(c.Writer)
+	files := make([]string, len(strips))
+	used := map[string]bool{}
+	for i, strip := range strips {
+		var obj fetchedObject
+		select {
+		case obj = <-slots[i]:
+			<-sem
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+		if obj.err != nil {
+			log.Printf("DownloadArchive Storage Error (%s): %v", strip.ID, obj.err)
+			continue
+		}
+
+		files[i] = uniqueArchiveName(downloadFilename(strip, "png"), used)
+		w, err := zw.CreateHeader(&zip.FileHeader{Name: files[i], Method: zip.Store, Modified: strip.CreatedAt})
+		if err != nil {
+			return err
+		}
+		if _, err := w.Write(obj.data); err != nil {
+			return err
+		}
+	}
+
+	// 3. The manifest, noting any image that couldn't be read
+	w, err := zw.Create("manifest.csv")
+	if err != nil {
+		return err
+	}
+	cw := csv.NewWriter(w)
+	if err := cw.Write([]string{"file", "id", "short_code", "title", "caption", "created_at", "visibility", "note"}); err != nil {
+		return err
+	}
+	for i, strip := range strips {
+		shortCode, note := "", ""
+		if strip.ShortCode != nil {
+			shortCode = *strip.ShortCode
+		}
+		if files[i] == "" {
+			note = "image unavailable"
+		}
+		if err := cw.Write([]string{files[i], strip.ID, shortCode, csvCell(strip.Title), csvCell(strip.Caption), strip.CreatedAt.Format(time.RFC3339), strip.Visibility, note}); err != nil {
+			return err
+		}
+	}
+	cw.Flush()
+	if err := cw.Error(); err != nil {
+		return err
+	}
+
+	return zw.Close()
+}
+
+// csvCell keeps user text from being read as a formula when the manifest is
+// opened in a spreadsheet, by prefixing it with a quote.
+func csvCell(s string) string {
+	if s != "" && strings.ContainsRune("=+-@\t\r", rune(s[0])) {
+		return "'" + s
+	}
+	return s
+}
+
+// uniqueArchiveName numbers repeated names: a.png, a-2.png, a-3.png.
+func uniqueArchiveName(name string, used map[string]bool) string {
+	candidate := name
+	dot := strings.LastIndex(name, ".")
+	for n := 2; used[candidate]; n++ {
+		candidate = fmt.Sprintf("%s-%d%s", name[:dot], n, name[dot:])
+	}
+	used[candidate] = true
+	return candidate
+}
@@ -0,0 +1,259 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+)
+
+const (
+	maxBulkItems   = 100
+	bulkWorkers    = 8
+	maxExtendDays  = 365
+	bulkItemFailed = "Internal error"
+)
+
+// Bulk actions.
+const (
+	bulkDelete        = "delete"         // Move to the trash
+	bulkPurge         = "purge"          // Permanently delete strips already in the trash
+	bulkSetVisibility = "set_visibility" // Needs visibility
+	bulkAddToAlbum    = "add_to_album"   // Needs album_id; owners only
+	bulkTag           = "tag"            // Needs tags; owners only
+	bulkExtendExpiry  = "extend_expiry"  // Optional days, defaults to the guest period; admins only
+)
+
+// bulkItemError is a per-item failure that is safe to show to the caller.
+type bulkItemError string
+
+func (e bulkItemError) Error() string { return string(e) }
+
+// bulkResult is the outcome for one strip.
+type bulkResult struct {
+	ID    string `json:"id"`
+	OK    bool   `json:"ok"`
+	Error string `json:"error,omitempty"`
+}
+
+type bulkRequest struct {
+	IDs        []string `json:"ids"`
+	Action     string   `json:"action"`
+	Visibility string   `json:"visibility"`
+	AlbumID    string   `json:"album_id"`
+	Tags       []string `json:"tags"`
+	Days       int      `json:"days"`
+}
+
+// BulkStrips applies one action to many of the caller's strips.
+func (h *Handler) BulkStrips(c *gin.Context) {
+	h.bulkStrips(c, c.GetString("user_id"))
+}
+
+// AdminBulkStrips applies one action to any strips. Album and tag actions are
+// per-user and not available here.
+func (h *Handler) AdminBulkStrips(c *gin.Context) {
+	h.bulkStrips(c, "")
+}
+
+// bulkStrips runs the action on each strip in a bounded worker pool and
+// reports success or failure per strip. ownerID limits the strips to one
+// user's; empty means any strip (admins).
+func (h *Handler) bulkStrips(c *gin.Context, ownerID string) {
+	var req bulkRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	ids := uniqueStrings(req.IDs)
+	if len(ids) == 0 || len(ids) > maxBulkItems {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("ids must contain between 1 and %d strip IDs", maxBulkItems)})
+		return
+	}
+
+	// 1. Validate the action and its parameters once, up front
+	apply, err := h.bulkAction(req, ownerID)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 2. Per-item authorization: only the caller's strips (or any, for admins)
+	query := h.DB.Unscoped().Where("id IN ?", ids)
+	if ownerID != "" {
+		query = query.Where("user_id = ?", ownerID)
+	}
+	var strips []models.Strip
+	if err := query.Find(&strips).Error; err != nil {
+		log.Printf("BulkStrips DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load strips"})
+		return
+	}
+	byID := make(map[string]models.Strip, len(strips))
+	for _, s := range strips {
+		byID[s.ID] = s
+	}
+
+	results := make([]bulkResult, len(ids))
+	var eligible []int
+	for i, id := range ids {
+		results[i].ID = id
+		strip, ok := byID[id]
+		switch {
+		case !ok, req.Action != bulkPurge && strip.DeletedAt.Valid:
+			results[i].Error = "Strip not found"
+		case req.Action == bulkPurge && !strip.DeletedAt.Valid:
+			results[i].Error = "Strip is not in the trash"
+		default:
+			eligible = append(eligible, i)
+		}
+	}
+
+	// 3. Albums take all strips in one ordered append; everything else goes
+	// item by item through the pool
+	if req.Action == bulkAddToAlbum {
+		h.bulkAddToAlbum(req.AlbumID, ids, eligible, results)
+	} else {
+		h.runBulk(c.Request.Context(), byID, ids, eligible, results, apply)
+	}
+
+	succeeded := 0
+	for _, r := range results {
+		if r.OK {
+			succeeded++
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{
+		"action":    req.Action,
+		"succeeded": succeeded,
+		"failed":    len(results) - succeeded,
+		"results":   results,
+	})
+}
+
+// runBulk applies fn to the eligible strips with at most bulkWorkers at once.
+func (h *Handler) runBulk(ctx context.Context, byID map[string]models.Strip, ids []string, eligible []int, results []bulkResult, fn func(context.Context, models.Strip) error) {
+	jobs := make(chan int)
+	var wg sync.WaitGroup
+	for w := 0; w < min(bulkWorkers, len(eligible)); w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range jobs {
+				results[i] = bulkOutcome(ids[i], fn(ctx, byID[ids[i]]))
+			}
+		}()
+	}
+	for _, i := range eligible {
+		jobs <- i
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+func (h *Handler) bulkAddToAlbum(albumID string, ids []string, eligible []int, results []bulkResult) {
+	stripIDs := make([]string, 0, len(eligible))
+	for _, i := range eligible {
+		stripIDs = append(stripIDs, ids[i])
+	}
+	err := appendAlbumStrips(h.DB, albumID, stripIDs)
+	for _, i := range eligible {
+		results[i] = bulkOutcome(ids[i], err)
+	}
+}
+
+func bulkOutcome(id string, err error) bulkResult {
+	if err == nil {
+		return bulkResult{ID: id, OK: true}
+	}
+	var itemErr bulkItemError
+	if errors.As(err, &itemErr) {
+		return bulkResult{ID: id, Error: itemErr.Error()}
+	}
+	log.Printf("BulkStrips Error (%s): %v", id, err)
+	return bulkResult{ID: id, Error: bulkItemFailed}
+}
+
+// bulkAction validates the request's parameters and returns the per-strip
+// operation. Album appends are handled separately by bulkAddToAlbum.
+func (h *Handler) bulkAction(req bulkRequest, ownerID string) (func(context.Context, models.Strip) error, error) {
+	switch req.Action {
+	case bulkDelete:
+		return func(_ context.Context, strip models.Strip) error {
+			return h.DB.Delete(&strip).Error
+		}, nil
+
+	case bulkPurge:
+		return func(ctx context.Context, strip models.Strip) error {
+			h.deleteStripObject(ctx, strip)
+			return h.DB.Unscoped().Delete(&strip).Error
+		}, nil
+
+	case bulkSetVisibility:
+		if !models.ValidVisibility(req.Visibility) {
+			return nil, errors.New("visibility must be private, unlisted or public")
+		}
+		return func(_ context.Context, strip models.Strip) error {
+			return h.DB.Model(&strip).Update("visibility", req.Visibility).Error
+		}, nil
+
+	case bulkAddToAlbum:
+		if ownerID == "" {
+			return nil, errors.New("add_to_album is only available to strip owners")
+		}
+		var album models.Album
+		if err := h.DB.Where("id = ? AND user_id = ?", req.AlbumID, ownerID).First(&album).Error; err != nil {
+			return nil, errors.New("Album not found")
+		}
+		return nil, nil
+
+	case bulkTag:
+		if ownerID == "" {
+			return nil, errors.New("tag is only available to strip owners")
+		}
+		slugs, names := normalizeTagNames(req.Tags)
+		if len(slugs) == 0 {
+			return nil, errors.New("tags must contain letters or digits")
+		}
+		return func(_ context.Context, strip models.Strip) error {
+			err := attachTags(h.DB, ownerID, strip.ID, slugs, names)
+			if errors.Is(err, errTooManyTags) {
+				return bulkItemError(fmt.Sprintf("A strip can have at most %d tags", maxTagsPerStrip))
+			}
+			return err
+		}, nil
+
+	case bulkExtendExpiry:
+		// Only guest strips expire, and they have no owner to ask for it
+		if ownerID != "" {
+			return nil, errors.New("extend_expiry is only available to admins")
+		}
+		days := req.Days
+		if days == 0 {
+			days = h.GuestExpirationDays
+		}
+		if days < 1 || days > maxExtendDays {
+			return nil, fmt.Errorf("days must be between 1 and %d", maxExtendDays)
+		}
+		return func(_ context.Context, strip models.Strip) error {
+			if strip.ExpiresAt == nil {
+				return bulkItemError("Strip does not expire")
+			}
+			// Expired strips that haven't been cleaned up yet count from now
+			from := *strip.ExpiresAt
+			if from.Before(time.Now()) {
+				from = time.Now()
+			}
+			return h.DB.Model(&strip).Update("expires_at", from.AddDate(0, 0, days)).Error
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unknown action %q", req.Action)
+	}
+}
@@ -0,0 +1,109 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"image/draw"
+	"image/jpeg"
+	"io"
+	"log"
+	"mime"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+)
+
+const downloadJPEGQuality = 92
+
+// DownloadStrip streams a strip's image as an attachment with a readable
+// filename. ?format=png (default) serves the stored original; ?format=jpeg
+// converts it. Access follows GetPublicStrip, except that owners can also
+// download their private strips.
+func (h *Handler) DownloadStrip(c *gin.Context) {
+	format := strings.ToLower(c.DefaultQuery("format", "png"))
+	if format == "jpg" {
+		format = "jpeg"
+	}
+	if format != "png" && format != "jpeg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or jpeg"})
+		return
+	}
+
+	strip, ok := h.viewableStrip(c)
+	if !ok {
+		return
+	}
+
+	// 1. Open the stored object
+	key := storageKey(strip.FileURL)
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory has no image"})
+		return
+	}
+	obj, err := h.openObject(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("DownloadStrip Storage Error: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read from storage"})
+		return
+	}
+	defer obj.Body.Close()
+
+	// 2. Count it before streaming; a dropped connection still counts
+	if err := h.DB.Model(&models.Strip{}).Where("id = ?", strip.ID).
+		UpdateColumn("download_count", gorm.Expr("download_count + 1")).Error; err != nil {
+		log.Printf("DownloadStrip DB Error: %v", err)
+	}
+
+	c.Header("Content-Disposition", mime.FormatMediaType("attachment", map[string]string{
+		"filename": downloadFilename(strip, format),
+	}))
+	c.Header("Cache-Control", "private, max-age=300")
+
+	// 3. Stream the original, or convert
+	if format == "png" {
+		c.Header("Content-Type", "image/png")
+		if obj.ContentLength != nil {
+			c.Header("Content-Length", fmt.Sprint(*obj.ContentLength))
+		}
+		c.Status(http.StatusOK)
+		if _, err := io.Copy(c.Writer, obj.Body); err != nil {
+			log.Printf("DownloadStrip Stream Error: %v", err)
+		}
+		return
+	}
+
+	img, err := decodeImageLimited(obj.Body)
+	if err != nil {
+		log.Printf("DownloadStrip Decode Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to convert image"})
+		return
+	}
+
+	// JPEG has no alpha: the transparent rounded corners would come out black
+	flat := image.NewRGBA(img.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), img, img.Bounds().Min, draw.Over)
+
+	c.Header("Content-Type", "image/jpeg")
+	c.Status(http.StatusOK)
+	if err := jpeg.Encode(c.Writer, flat, &jpeg.Options{Quality: downloadJPEGQuality}); err != nil {
+		log.Printf("DownloadStrip Encode Error: %v", err)
+	}
+}
+
+// downloadFilename builds e.g. "wuby-beach-day-2024-06-01.jpg" from the
+// strip's title and date.
+func downloadFilename(strip models.Strip, format string) string {
+	name := tagSlug(strip.Title)
+	if name == "" {
+		name = "strip"
+	}
+	ext := "png"
+	if format == "jpeg" {
+		ext = "jpg"
+	}
+	return fmt.Sprintf("wuby-%s-%s.%s", name, strip.CreatedAt.Format("2006-01-02"), ext)
+}
@@ -0,0 +1,184 @@
+package handlers
+
+import (
+	"fmt"
+	"log"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/tokens"
+)
+
+// ManageTokenHeader carries the per-strip token issued by GuestSaveStrip.
+const ManageTokenHeader = "X-Manage-Token"
+
+func (h *Handler) guestManageURL(stripID, token string) string {
+	return fmt.Sprintf("%s/manage/%s?token=%s", h.AppURL, stripID, url.QueryEscape(token))
+}
+
+// reissueGuestTokens fills fresh claim and management tokens into a replayed
+// GuestSaveStrip response, since the originals were never stored. The old
+// tokens stop working; a strip claimed in the meantime gets none.
+func (h *Handler) reissueGuestTokens(resp map[string]interface{}) error {
+	id, _ := resp["id"].(string)
+	claimToken, err := tokens.Generate()
+	if err != nil {
+		return err
+	}
+	manageToken, err := tokens.Generate()
+	if err != nil {
+		return err
+	}
+
+	res := h.DB.Model(&models.Strip{}).
+		Where("id = ? AND user_id IS NULL", id).
+		Updates(map[string]interface{}{
+			"claim_token_hash":  tokens.Hash(claimToken),
+			"manage_token_hash": tokens.Hash(manageToken),
+		})
+	if res.Error != nil || res.RowsAffected == 0 {
+		return res.Error
+	}
+
+	resp["claim_token"] = claimToken
+	resp["manage_token"] = manageToken
+	resp["manage_url"] = h.guestManageURL(id, manageToken)
+	return nil
+}
+
+// guestStripForManage loads an unclaimed guest strip and checks the management
+// token. It writes the error response itself and returns false on failure.
+func (h *Handler) guestStripForManage(c *gin.Context) (models.Strip, bool) {
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id IS NULL", c.Param("id")).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return strip, false
+	}
+
+	if !tokens.Matches(c.GetHeader(ManageTokenHeader), strip.ManageTokenHash) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid management token"})
+		return strip, false
+	}
+
+	if strip.ExpiresAt != nil && time.Now().After(*strip.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "This memory has expired"})
+		return strip, false
+	}
+
+	return strip, true
+}
+
+func (h *Handler) GuestGetStrip(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+	c.JSON(http.StatusOK, strip)
+}
+
+func (h *Handler) GuestUpdateStrip(c *gin.Context) {
+	var req struct {
+		Title   string `json:"title"`
+		Caption string `json:"caption"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+
+	if req.Title != "" {
+		strip.Title = req.Title
+	}
+	if req.Caption != "" {
+		strip.Caption = req.Caption
+	}
+
+	if err := h.DB.Save(&strip).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update strip"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strip updated", "strip": strip})
+}
+
+func (h *Handler) GuestDeleteStrip(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+
+	// Guests have no trash to restore from, so this is permanent
+	h.deleteStripObject(c.Request.Context(), strip)
+
+	if err := h.DB.Unscoped().Delete(&strip).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete strip"})
+		return
+	}
+
+	log.Printf("GuestDeleteStrip: guest deleted strip %s", strip.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Strip deleted"})
+}
+
+// GuestExtendStrip pushes the expiry back by another guest period. Allowed once.
+func (h *Handler) GuestExtendStrip(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+
+	if strip.ExpiryExtended || strip.ExpiresAt == nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "This memory has already been extended"})
+		return
+	}
+
+	expiresAt := strip.ExpiresAt.AddDate(0, 0, h.GuestExpirationDays)
+	res := h.DB.Model(&models.Strip{}).
+		Where("id = ? AND expiry_extended = ?", strip.ID, false).
+		Updates(map[string]interface{}{
+			"expires_at":      expiresAt,
+			"expiry_extended": true,
+		})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to extend strip"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This memory has already been extended"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Expiry extended", "expires_at": expiresAt})
+}
+
+func (h *Handler) GuestReplaceStripImage(c *gin.Context) {
+	var req struct {
+		Image string `json:"image"` // Base64
+		Amend bool   `json:"amend"` // Replace without keeping the old image as a version
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	imgBytes, err := decodeImage(req.Image)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
+		return
+	}
+
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+
+	h.replaceStripImage(c, strip, imgBytes, nil, req.Amend)
+}
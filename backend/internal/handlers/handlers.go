@@ -1,43 +1,60 @@
 package handlers
 
 import (
-	"bytes"
 	"context"
-	"encoding/base64"
-	"fmt"
 	"log"
 	"net/http"
-	"net/url"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
 	"github.com/aws/aws-sdk-go-v2/service/s3"
-	"github.com/aws/aws-sdk-go-v2/service/s3/types"
 	"github.com/gin-gonic/gin"
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
+	"web-photobooth/backend/internal/config"
+	"web-photobooth/backend/internal/mailer"
 	"web-photobooth/backend/internal/middleware"
 	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/password"
+	"web-photobooth/backend/internal/tokens"
 )
 
 type Handler struct {
 	DB                  *gorm.DB
 	S3Client            *s3.Client
+	Mailer              mailer.Mailer
+	Passwords           password.Hasher
 	Bucket              string
 	JWTSecret           string
 	GuestExpirationDays int
+	AppURL              string
+	ShareBaseURL        string
+	MagicLinkTTL        time.Duration
+	MagicLinkMaxPerHour int
+	Cookies             CookieSettings
+	TrashRetention      time.Duration
+
+	// Failed short code lookups per client IP
+	shortCodeMisses *missLimiter
 }
 
-func NewHandler(db *gorm.DB, s3Client *s3.Client, bucket string, jwtSecret string, guestDays int) *Handler {
+func NewHandler(db *gorm.DB, s3Client *s3.Client, m mailer.Mailer, hasher password.Hasher, cfg *config.Config) *Handler {
 	return &Handler{
 		DB:                  db,
 		S3Client:            s3Client,
-		Bucket:              bucket,
-		JWTSecret:           jwtSecret,
-		GuestExpirationDays: guestDays,
+		Mailer:              m,
+		Passwords:           hasher,
+		Bucket:              cfg.DOBucket,
+		JWTSecret:           cfg.JWTSecret,
+		GuestExpirationDays: cfg.GuestExpirationDays,
+		AppURL:              strings.TrimRight(cfg.AppURL, "/"),
+		ShareBaseURL:        strings.TrimRight(cfg.ShareBaseURL, "/"),
+		MagicLinkTTL:        time.Duration(cfg.MagicLinkTTLMinutes) * time.Minute,
+		MagicLinkMaxPerHour: cfg.MagicLinkMaxPerHour,
+		Cookies:             newCookieSettings(cfg),
+		TrashRetention:      time.Duration(cfg.TrashRetentionDays) * 24 * time.Hour,
+		shortCodeMisses:     newMissLimiter(shortCodeMissLimit, shortCodeMissWindow),
 	}
 }
 
@@ -49,27 +66,93 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 			auth.POST("/signup", h.Signup)
 			auth.POST("/login", h.Login)
 			auth.POST("/sync", h.SyncUser)
+			auth.POST("/magic-link", h.RequestMagicLink)
+			auth.POST("/magic-link/redeem", h.RedeemMagicLink)
+			auth.POST("/logout", middleware.CSRFMiddleware(), h.Logout)
 		}
 
 		strips := api.Group("/strips")
 		{
 			// Public routes (no auth)
-			strips.POST("/guest-save", h.GuestSaveStrip)
+			strips.POST("/guest-save", h.Idempotency(h.reissueGuestTokens), h.GuestSaveStrip)
+			strips.GET("/public", h.GetPublicStrips)
 			strips.GET("/public/:id", h.GetPublicStrip)
+			strips.GET("/:id/download", middleware.OptionalAuthMiddleware(h.JWTSecret, h.userActive), h.DownloadStrip)
+			strips.GET("/:id/qr", middleware.OptionalAuthMiddleware(h.JWTSecret, h.userActive), h.GetStripQR)
+			strips.GET("/:id/og.png", h.GetStripOGImage)
+			strips.GET("/:id/meta", h.GetStripMeta)
+			strips.GET("/:id/image", h.GetStripImage)
+
+			// Guest self-management (X-Manage-Token)
+			strips.GET("/guest/:id", h.GuestGetStrip)
+			strips.PATCH("/guest/:id", h.GuestUpdateStrip)
+			strips.DELETE("/guest/:id", h.GuestDeleteStrip)
+			strips.POST("/guest/:id/extend", h.GuestExtendStrip)
+			strips.PUT("/guest/:id/image", h.GuestReplaceStripImage)
+			strips.POST("/guest/:id/rerender", h.GuestRerenderStrip)
+			strips.GET("/guest/:id/versions", h.GuestListStripVersions)
+			strips.POST("/guest/:id/versions/:versionId/restore", h.GuestRestoreStripVersion)
+			strips.DELETE("/guest/:id/versions", h.GuestPruneStripVersions)
 
 			// Protected routes
 			protected := strips.Group("/")
-			protected.Use(middleware.AuthMiddleware(h.JWTSecret))
+			protected.Use(middleware.AuthMiddleware(h.JWTSecret, h.userActive))
 			{
-				protected.POST("/save", h.SaveStrip)
+				protected.POST("/save", h.Idempotency(nil), h.SaveStrip)
 				protected.GET("/my-strips", h.GetMyStrips)
+				protected.GET("/trash", h.GetTrashedStrips)
+				protected.POST("/bulk", h.BulkStrips)
+				protected.GET("/archive", h.DownloadArchive)
+				protected.POST("/:id/restore", h.RestoreStrip)
 				protected.PATCH("/:id", h.UpdateStrip)
+				protected.POST("/:id/claim", h.ClaimStrip)
+				protected.PUT("/:id/image", h.ReplaceStripImage)
+				protected.POST("/:id/rerender", h.RerenderStrip)
+				protected.GET("/:id/versions", h.ListStripVersions)
+				protected.POST("/:id/versions/:versionId/restore", h.RestoreStripVersion)
+				protected.DELETE("/:id/versions", h.PruneStripVersions)
 				protected.DELETE("/:id", h.DeleteStrip)
+				protected.POST("/:id/tags", h.AddStripTags)
+				protected.DELETE("/:id/tags/:slug", h.RemoveStripTag)
+				protected.POST("/:id/share-links", h.CreateShareLink)
+				protected.GET("/:id/share-links", h.ListShareLinks)
+				protected.DELETE("/:id/share-links/:linkId", h.RevokeShareLink)
+				protected.POST("/:id/transfers", h.CreateStripTransfer)
 			}
 		}
 
+		// Public routes (no auth)
+		api.GET("/albums/public/:slug", h.GetPublicAlbum)
+		api.GET("/share/:slug", h.ResolveShareLink)
+		api.GET("/share/:slug/image", h.GetShareLinkImage)
+		api.GET("/oembed", h.OEmbed)
+
+		albums := api.Group("/albums")
+		albums.Use(middleware.AuthMiddleware(h.JWTSecret, h.userActive))
+		{
+			albums.GET("", h.ListAlbums)
+			albums.POST("", h.CreateAlbum)
+			albums.GET("/:id", h.GetAlbum)
+			albums.PATCH("/:id", h.UpdateAlbum)
+			albums.DELETE("/:id", h.DeleteAlbum)
+			albums.POST("/:id/strips", h.AddAlbumStrips)
+			albums.PUT("/:id/strips", h.ReorderAlbumStrips)
+			albums.DELETE("/:id/strips/:stripId", h.RemoveAlbumStrip)
+		}
+
+		api.GET("/tags", middleware.AuthMiddleware(h.JWTSecret, h.userActive), h.ListTags)
+
+		transfers := api.Group("/transfers")
+		transfers.Use(middleware.AuthMiddleware(h.JWTSecret, h.userActive))
+		{
+			transfers.GET("", h.ListTransfers)
+			transfers.POST("/:id/accept", h.AcceptStripTransfer)
+			transfers.POST("/:id/decline", h.DeclineStripTransfer)
+			transfers.DELETE("/:id", h.CancelStripTransfer)
+		}
+
 		admin := api.Group("/admin")
-		admin.Use(middleware.AuthMiddleware(h.JWTSecret))
+		admin.Use(middleware.AuthMiddleware(h.JWTSecret, h.userActive))
 		admin.Use(func(c *gin.Context) {
 			if !c.GetBool("is_admin") {
 				c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
@@ -84,7 +167,17 @@ func (h *Handler) RegisterRoutes(r *gin.Engine) {
 			admin.PATCH("/users/:id/role", h.AdminUpdateUserRole)
 			admin.GET("/strips", h.AdminGetStrips)
 			admin.DELETE("/strips/:id", h.AdminDeleteStrip)
+			admin.POST("/strips/bulk", h.AdminBulkStrips)
+			admin.POST("/strips/:id/short-code", h.AdminRegenerateShortCode)
+			admin.POST("/strips/:id/transfer", h.AdminTransferStrip)
+			admin.GET("/audit-log", h.AdminGetAuditLog)
 			admin.DELETE("/users/:id", h.AdminDeleteUser)
+			admin.GET("/tags/popular", h.AdminGetPopularTags)
+			admin.GET("/stats/styles", h.AdminGetStyleStats)
+			admin.GET("/trash/strips", h.AdminGetTrashedStrips)
+			admin.GET("/trash/users", h.AdminGetTrashedUsers)
+			admin.POST("/strips/:id/restore", h.AdminRestoreStrip)
+			admin.POST("/users/:id/restore", h.AdminRestoreUser)
 		}
 	}
 }
@@ -126,18 +219,18 @@ func (h *Handler) Signup(c *gin.Context) {
 		return
 	}
 
-	// Double check duplicates
+	// Double check duplicates (trashed accounts still hold their email and username)
 	var existing models.User
-	if err := h.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
+	if err := h.DB.Unscoped().Where("email = ?", req.Email).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Email already in use"})
 		return
 	}
-	if err := h.DB.Where("username = ?", req.Username).First(&existing).Error; err == nil {
+	if err := h.DB.Unscoped().Where("username = ?", req.Username).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := h.Passwords.Hash(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel password"})
 		return
@@ -147,7 +240,7 @@ func (h *Handler) Signup(c *gin.Context) {
 		ID:       uuid.New().String(),
 		Username: req.Username,
 		Email:    req.Email,
-		Password: string(hashed),
+		Password: hashed,
 	}
 
 	if err := h.DB.Create(&user).Error; err != nil {
@@ -175,15 +268,34 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(req.Password)); err != nil {
+	ok, needsRehash, err := h.Passwords.Verify(req.Password, user.Password)
+	if err != nil {
+		log.Printf("Login: password verification error for user %s: %v", user.ID, err)
+	}
+	if !ok {
 		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid credentials"})
 		return
 	}
 
+	// Transparently upgrade legacy bcrypt or outdated argon2id hashes
+	if needsRehash {
+		if hashed, err := h.Passwords.Hash(req.Password); err != nil {
+			log.Printf("Login: rehash failed for user %s: %v", user.ID, err)
+		} else if err := h.DB.Model(&user).Update("password", hashed).Error; err != nil {
+			log.Printf("Login: failed to store upgraded hash for user %s: %v", user.ID, err)
+		}
+	}
+
+	h.issueSession(c, user)
+}
+
+// issueSession signs a JWT for the user and writes the standard login response.
+// In cookie mode the token is set as an HttpOnly cookie instead of returned.
+func (h *Handler) issueSession(c *gin.Context, user models.User) {
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id":  user.ID,
 		"is_admin": user.IsAdmin,
-		"exp":      time.Now().Add(time.Hour * 72).Unix(),
+		"exp":      time.Now().Add(sessionTTL).Unix(),
 	})
 
 	tokenString, err := token.SignedString([]byte(h.JWTSecret))
@@ -192,15 +304,27 @@ func (h *Handler) Login(c *gin.Context) {
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{
-		"access_token": tokenString,
+	resp := gin.H{
 		"user": gin.H{
 			"id":       user.ID,
 			"username": user.Username,
 			"email":    user.Email,
 			"is_admin": user.IsAdmin,
 		},
-	})
+	}
+
+	if h.Cookies.Enabled {
+		csrfToken, err := h.setSessionCookies(c, tokenString)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate token"})
+			return
+		}
+		resp["csrf_token"] = csrfToken
+	} else {
+		resp["access_token"] = tokenString
+	}
+
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *Handler) SyncUser(c *gin.Context) {
@@ -210,197 +334,312 @@ func (h *Handler) SyncUser(c *gin.Context) {
 func (h *Handler) SaveStrip(c *gin.Context) {
 	userID := c.GetString("user_id")
 	var req struct {
-		ID      string `json:"id"`
-		Image   string `json:"image"` // Base64
-		Title   string `json:"title"`
-		Caption string `json:"caption"`
+		Image          string                 `json:"image"` // Base64
+		Title          string                 `json:"title"`
+		Caption        string                 `json:"caption"`
+		Visibility     string                 `json:"visibility"`      // Optional, defaults to unlisted
+		RenderSettings *models.RenderSettings `json:"render_settings"` // Optional
+		Shots          []string               `json:"shots"`           // Optional base64 originals, kept for re-rendering
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
-
-	// 1. Decode
-	b64data := req.Image
-	if idx := strings.Index(b64data, ","); idx != -1 {
-		b64data = b64data[idx+1:]
+	if req.Visibility == "" {
+		req.Visibility = models.VisibilityUnlisted
+	}
+	if !models.ValidVisibility(req.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be private, unlisted or public"})
+		return
+	}
+	if req.RenderSettings != nil {
+		if err := validateRenderSettings(req.RenderSettings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	imgBytes, err := base64.StdEncoding.DecodeString(b64data)
+	// 1. Decode
+	imgBytes, err := decodeImage(req.Image)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
 		return
 	}
-
-	// 2. Prepare IDs and Filename
-	stripID := req.ID
-	if stripID == "" {
-		stripID = uuid.New().String()
+	var shots [][]byte
+	if len(req.Shots) > 0 {
+		if shots, err = decodeShots(req.Shots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.RenderSettings != nil && req.RenderSettings.PhotoCount != len(shots) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "render_settings.photo_count must match the number of shots"})
+			return
+		}
 	}
-	fileName := fmt.Sprintf("strips/%s/%s.png", userID, stripID)
 
-	// 3. Upload to S3 (DigitalOcean Spaces)
-	_, err = h.S3Client.PutObject(c.Request.Context(), &s3.PutObjectInput{
-		Bucket:      aws.String(h.Bucket),
-		Key:         aws.String(fileName),
-		Body:        bytes.NewReader(imgBytes),
-		ContentType: aws.String("image/png"),
-		ACL:         types.ObjectCannedACLPublicRead,
-	})
+	// 2. Prepare IDs and Filename. IDs are always minted by the server;
+	// replacing an existing strip's image goes through PUT /:id/image.
+	stripID := uuid.New().String()
+	fileName := stripObjectKey(userID, stripID)
 
+	// 3. Upload to S3 (DigitalOcean Spaces)
+	fileURL, err := h.uploadObject(c.Request.Context(), fileName, imgBytes, "image/png")
 	if err != nil {
 		log.Printf("S3 Upload Error: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
 		return
 	}
+	shotKeys, err := h.storeShots(c.Request.Context(), userID, stripID, shots)
+	if err != nil {
+		log.Printf("S3 Upload Error (Shots): %v", err)
+		h.deleteObject(context.Background(), fileName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+		return
+	}
 
-	// 3. Construct File URL using CDN
-	fileURL := fmt.Sprintf("https://%s.sgp1.cdn.digitaloceanspaces.com/%s", h.Bucket, fileName)
-
-	// 4. Save to DB (already have stripID)
-	log.Printf("SaveStrip: userID=%s, stripID=%s, fileURL=%s", userID, stripID, fileURL)
-	// 4. Save to DB (already have stripID)
+	// 4. Save to DB
 	uid := userID
 	log.Printf("SaveStrip: Writing to DB - userID=%s, stripID=%s", uid, stripID)
-	
-	strip := models.Strip{
-		ID:        stripID,
-		UserID:    &uid,
-		Title:     req.Title,
-		FileURL:   fileURL,
-		Caption:   req.Caption,
-		CreatedAt: time.Now(),
-	}
 
-	if err := h.DB.Create(&strip).Error; err != nil {
+	strip := models.Strip{
+		ID:             stripID,
+		UserID:         &uid,
+		Title:          req.Title,
+		FileURL:        fileURL,
+		Caption:        req.Caption,
+		Visibility:     req.Visibility,
+		RenderSettings: req.RenderSettings,
+		ShotKeys:       shotKeys,
+		CreatedAt:      time.Now(),
+	}
+
+	if err := h.createWithShortCode(&strip); err != nil {
 		log.Printf("SaveStrip DB Error: %v", err)
+		h.deleteStripObject(context.Background(), strip)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to database"})
 		return
 	}
-	
-	uidVal := "NIL"
-	if strip.UserID != nil {
-		uidVal = *strip.UserID
-	}
-	log.Printf("SaveStrip SUCCESS: id=%s, stored_user_id=%s", strip.ID, uidVal)
+
+	log.Printf("SaveStrip SUCCESS: id=%s, stored_user_id=%s", strip.ID, uid)
 
 	// Return ID so frontend can update it later
 	c.JSON(http.StatusOK, gin.H{
-		"message":  "Strip saved successfully",
-		"file_url": fileURL,
-		"id":       strip.ID,
+		"message":    "Strip saved successfully",
+		"file_url":   fileURL,
+		"id":         strip.ID,
+		"short_code": strip.ShortCode,
 	})
 }
 
 func (h *Handler) GuestSaveStrip(c *gin.Context) {
 	var req struct {
-		ID      string `json:"id"`
-		Image   string `json:"image"` // Base64
-		Title   string `json:"title"`
-		Caption string `json:"caption"`
+		Image          string                 `json:"image"` // Base64
+		Title          string                 `json:"title"`
+		Caption        string                 `json:"caption"`
+		RenderSettings *models.RenderSettings `json:"render_settings"` // Optional
+		Shots          []string               `json:"shots"`           // Optional base64 originals, kept for re-rendering
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
-
-	// 1. Decode
-	b64data := req.Image
-	if idx := strings.Index(b64data, ","); idx != -1 {
-		b64data = b64data[idx+1:]
+	if req.RenderSettings != nil {
+		if err := validateRenderSettings(req.RenderSettings); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
 	}
 
-	imgBytes, err := base64.StdEncoding.DecodeString(b64data)
+	// 1. Decode
+	imgBytes, err := decodeImage(req.Image)
 	if err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
 		return
 	}
-
-	// 2. Prepare IDs and Filename
-	log.Printf("Incoming request ID: %s", req.ID)
-	stripID := req.ID
-	if stripID == "" {
-		stripID = uuid.New().String()
-		log.Printf("No ID provided, generated new: %s", stripID)
+	var shots [][]byte
+	if len(req.Shots) > 0 {
+		if shots, err = decodeShots(req.Shots); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+		if req.RenderSettings != nil && req.RenderSettings.PhotoCount != len(shots) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "render_settings.photo_count must match the number of shots"})
+			return
+		}
 	}
-	fileName := fmt.Sprintf("strips/guest/%s.png", stripID)
-	log.Printf("Final fileName for upload: %s", fileName)
 
-	// 3. Upload to S3
-	_, err = h.S3Client.PutObject(c.Request.Context(), &s3.PutObjectInput{
-		Bucket:      aws.String(h.Bucket),
-		Key:         aws.String(fileName),
-		Body:        bytes.NewReader(imgBytes),
-		ContentType: aws.String("image/png"),
-		ACL:         types.ObjectCannedACLPublicRead,
-	})
+	// 2. Prepare IDs and Filename (server-minted, never taken from the client)
+	stripID := uuid.New().String()
+	fileName := stripObjectKey("", stripID)
 
+	// 3. Claim and management tokens (only the hashes are stored)
+	claimToken, err := tokens.Generate()
 	if err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate claim token"})
+		return
+	}
+	manageToken, err := tokens.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate management token"})
 		return
 	}
 
-	// 3. Construct File URL
-	fileURL := fmt.Sprintf("https://%s.sgp1.cdn.digitaloceanspaces.com/%s", h.Bucket, fileName)
+	// 4. Upload to S3
+	fileURL, err := h.uploadObject(c.Request.Context(), fileName, imgBytes, "image/png")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+		return
+	}
+	shotKeys, err := h.storeShots(c.Request.Context(), "", stripID, shots)
+	if err != nil {
+		log.Printf("GuestSaveStrip Upload Error (Shots): %v", err)
+		h.deleteObject(context.Background(), fileName)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+		return
+	}
 
-	// 4. Expiration
+	// 5. Expiration
 	expiresAt := time.Now().AddDate(0, 0, h.GuestExpirationDays)
 
-	// 6. Save to DB (already have stripID)
+	// 6. Save to DB
 
 	strip := models.Strip{
-		ID:        stripID,
-		UserID:    nil,
-		Title:     req.Title,
-		FileURL:   fileURL,
-		Caption:   req.Caption,
-		IsGuest:   true,
-		ExpiresAt: &expiresAt,
-		CreatedAt: time.Now(),
-	}
-
-	if err := h.DB.Create(&strip).Error; err != nil {
+		ID:              stripID,
+		UserID:          nil,
+		Title:           req.Title,
+		FileURL:         fileURL,
+		Caption:         req.Caption,
+		IsGuest:         true,
+		Visibility:      models.VisibilityUnlisted, // Guests can't list or hide strips
+		RenderSettings:  req.RenderSettings,
+		ShotKeys:        shotKeys,
+		ExpiresAt:       &expiresAt,
+		CreatedAt:       time.Now(),
+		ClaimTokenHash:  tokens.Hash(claimToken),
+		ManageTokenHash: tokens.Hash(manageToken),
+	}
+
+	if err := h.createWithShortCode(&strip); err != nil {
 		log.Printf("DATABASE ERROR in GuestSaveStrip: %v", err)
+		h.deleteStripObject(context.Background(), strip)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to database"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{
-		"message":    "Guest strip saved successfully",
-		"file_url":   fileURL,
-		"id":         strip.ID,
-		"expires_at": expiresAt,
+		"message":      "Guest strip saved successfully",
+		"file_url":     fileURL,
+		"id":           strip.ID,
+		"short_code":   strip.ShortCode,
+		"expires_at":   expiresAt,
+		"claim_token":  claimToken,
+		"manage_token": manageToken,
+		"manage_url":   h.guestManageURL(strip.ID, manageToken),
 	})
 }
 
-func (h *Handler) GetPublicStrip(c *gin.Context) {
-	id := c.Param("id")
+// ReplaceStripImage swaps the image of a strip the caller owns.
+func (h *Handler) ReplaceStripImage(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Image string `json:"image"` // Base64
+		Amend bool   `json:"amend"` // Replace without keeping the old image as a version
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	imgBytes, err := decodeImage(req.Image)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Failed to decode image"})
+		return
+	}
+
 	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	h.replaceStripImage(c, strip, imgBytes, nil, req.Amend)
+}
 
-	if err := h.DB.First(&strip, id).Error; err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+func (h *Handler) GetPublicStrip(c *gin.Context) {
+	strip, ok := h.publicStrip(c)
+	if !ok {
 		return
 	}
 
+	c.JSON(http.StatusOK, strip)
+}
+
+// publicStrip loads the :id strip (UUID or short code) for anyone who has the
+// link. It writes the error response itself and returns false on failure.
+func (h *Handler) publicStrip(c *gin.Context) (models.Strip, bool) {
+	strip, status, msg := h.lookupPublicStrip(c, c.Param("id"))
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": msg})
+		return strip, false
+	}
+	return strip, true
+}
+
+// viewableStrip is publicStrip that also lets a signed-in owner through to
+// their own strips, private ones included. The route needs
+// OptionalAuthMiddleware.
+func (h *Handler) viewableStrip(c *gin.Context) (models.Strip, bool) {
+	if userID := c.GetString("user_id"); userID != "" {
+		var strip models.Strip
+		if err := stripByPublicID(h.DB, c.Param("id")).First(&strip, "strips.user_id = ?", userID).Error; err == nil {
+			return strip, true
+		}
+	}
+	return h.publicStrip(c)
+}
+
+// lookupPublicStrip applies the public access rules to a strip ID, returning
+// the status and message to answer with when it can't be shown.
+func (h *Handler) lookupPublicStrip(c *gin.Context, id string) (models.Strip, int, string) {
+	var strip models.Strip
+
+	// Clients that keep missing short codes are probably guessing them
+	_, uuidErr := uuid.Parse(id)
+	isCode := uuidErr != nil
+	if isCode && h.shortCodeMisses.blocked(c.ClientIP()) {
+		return strip, http.StatusTooManyRequests, "Too many lookups, try again later"
+	}
+
+	// Private strips are reported as missing so their IDs can't be probed
+	if err := stripByPublicID(h.DB, id).First(&strip, "visibility <> ?", models.VisibilityPrivate).Error; err != nil {
+		if isCode {
+			h.shortCodeMisses.miss(c.ClientIP())
+		}
+		return strip, http.StatusNotFound, "Memory not found"
+	}
+
 	// Double check expiration for guests
 	if strip.IsGuest && strip.ExpiresAt != nil && time.Now().After(*strip.ExpiresAt) {
-		c.JSON(http.StatusGone, gin.H{"error": "This memory has expired"})
-		return
+		return strip, http.StatusGone, "This memory has expired"
 	}
 
-	c.JSON(http.StatusOK, strip)
+	return strip, http.StatusOK, ""
+}
+
+// GetPublicStrips lists strips their owners have made public, newest first by
+// default. It takes the same paging, filters and search as GetMyStrips.
+func (h *Handler) GetPublicStrips(c *gin.Context) {
+	h.listStrips(c, h.DB.Model(&models.Strip{}).
+		Where("strips.visibility = ?", models.VisibilityPublic).
+		Where("(strips.expires_at IS NULL OR strips.expires_at > ?)", time.Now()), false, false)
 }
 
 func (h *Handler) GetMyStrips(c *gin.Context) {
 	userID := c.GetString("user_id")
-	var strips []models.Strip
-	if err := h.DB.Where("user_id = ?", userID).Order("created_at desc").Find(&strips).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
-		return
-	}
-	c.JSON(http.StatusOK, gin.H{"strips": strips})
+	h.listStrips(c, h.DB.Model(&models.Strip{}).Where("strips.user_id = ?", userID), false, true)
 }
 
 func (h *Handler) UpdateStrip(c *gin.Context) {
@@ -408,18 +647,23 @@ func (h *Handler) UpdateStrip(c *gin.Context) {
 	stripID := c.Param("id")
 
 	var req struct {
-		Title   string `json:"title"`
-		Caption string `json:"caption"`
+		Title      string `json:"title"`
+		Caption    string `json:"caption"`
+		Visibility string `json:"visibility"`
 	}
 
 	if err := c.ShouldBindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
 		return
 	}
+	if req.Visibility != "" && !models.ValidVisibility(req.Visibility) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "visibility must be private, unlisted or public"})
+		return
+	}
 
 	var strip models.Strip
 	log.Printf("UpdateStrip lookup: id=%s, user_id=%s", stripID, userID)
-	
+
 	// 1. Find the strip regardless of owner first
 	if err := h.DB.Where("id = ?", stripID).First(&strip).Error; err != nil {
 		log.Printf("UpdateStrip NOT FOUND: id=%s", stripID)
@@ -427,21 +671,13 @@ func (h *Handler) UpdateStrip(c *gin.Context) {
 		return
 	}
 
-	// 2. Ownership & Claiming Logic
-	if strip.UserID != nil && *strip.UserID != userID {
-		log.Printf("UpdateStrip FORBIDDEN: id=%s attempted update by %s but owned by %s", stripID, userID, *strip.UserID)
+	// 2. Ownership (guest strips must be claimed via /claim first)
+	if strip.UserID == nil || *strip.UserID != userID {
+		log.Printf("UpdateStrip FORBIDDEN: id=%s attempted update by %s", stripID, userID)
 		c.JSON(http.StatusForbidden, gin.H{"error": "You do not have permission to update this memory"})
 		return
 	}
 
-	// 3. If it was a guest strip, claim it for this user
-	if strip.UserID == nil {
-		log.Printf("UpdateStrip: Claiming guest strip %s for user %s", stripID, userID)
-		strip.UserID = &userID
-		strip.IsGuest = false
-		strip.ExpiresAt = nil // Permanent save
-	}
-
 	log.Printf("UpdateStrip PROCEEDING: id=%s, for user=%s", strip.ID, userID)
 
 	if req.Title != "" {
@@ -450,6 +686,9 @@ func (h *Handler) UpdateStrip(c *gin.Context) {
 	if req.Caption != "" {
 		strip.Caption = req.Caption
 	}
+	if req.Visibility != "" {
+		strip.Visibility = req.Visibility
+	}
 
 	if err := h.DB.Save(&strip).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update strip"})
@@ -459,6 +698,58 @@ func (h *Handler) UpdateStrip(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Strip updated", "strip": strip})
 }
 
+// ClaimStrip transfers a guest strip to the logged-in user. The caller must
+// present the claim token returned by GuestSaveStrip.
+func (h *Handler) ClaimStrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+
+	var req struct {
+		ClaimToken string `json:"claim_token"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.ClaimToken == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Claim token required"})
+		return
+	}
+
+	var strip models.Strip
+	if err := h.DB.Where("id = ?", stripID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	if strip.UserID != nil || !tokens.Matches(req.ClaimToken, strip.ClaimTokenHash) {
+		log.Printf("ClaimStrip FORBIDDEN: id=%s attempted claim by %s", stripID, userID)
+		c.JSON(http.StatusForbidden, gin.H{"error": "Invalid claim token"})
+		return
+	}
+
+	// Conditional update so two concurrent claims can't both succeed
+	res := h.DB.Model(&models.Strip{}).
+		Where("id = ? AND user_id IS NULL AND claim_token_hash = ?", strip.ID, strip.ClaimTokenHash).
+		Updates(map[string]interface{}{
+			"user_id":           userID,
+			"is_guest":          false,
+			"expires_at":        nil, // Permanent save
+			"claim_token_hash":  "",
+			"manage_token_hash": "", // The guest no longer owns it
+		})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to claim strip"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This memory has already been claimed"})
+		return
+	}
+
+	log.Printf("ClaimStrip: guest strip %s claimed by user %s", stripID, userID)
+
+	h.DB.First(&strip, "id = ?", strip.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Strip claimed", "strip": strip})
+}
+
 func (h *Handler) DeleteStrip(c *gin.Context) {
 	userID := c.GetString("user_id")
 	stripID := c.Param("id")
@@ -470,39 +761,15 @@ func (h *Handler) DeleteStrip(c *gin.Context) {
 		return
 	}
 
-	// 2. Delete from S3 (DigitalOcean Spaces)
-	if strip.FileURL != "" {
-		// Extract the key from the URL
-		// URL format: https://<bucket>.<endpoint>/<key>
-		// We can try to parse the URL
-		u, err := url.Parse(strip.FileURL)
-		if err == nil {
-			// Path usually starts with /, trim it
-			key := strings.TrimPrefix(u.Path, "/")
-			
-			// Safety check: key shouldn't be empty
-			if key != "" {
-				_, err := h.S3Client.DeleteObject(c.Request.Context(), &s3.DeleteObjectInput{
-					Bucket: aws.String(h.Bucket),
-					Key:    aws.String(key),
-				})
-				if err != nil {
-					log.Printf("Failed to delete S3 object %s: %v", key, err)
-					// We continue to delete from DB even if S3 fails, to keep DB consistent with user intent
-				} else {
-					log.Printf("Deleted S3 object: %s", key)
-				}
-			}
-		}
-	}
-
-	// 3. Delete from DB
+	// 2. Move to the trash. The image, its versions and the shots stay in
+	// storage until PurgeTrash runs after the retention window, so the strip
+	// can still be restored.
 	if err := h.DB.Delete(&strip).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete strip"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Strip deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Strip moved to trash"})
 }
 
 func (h *Handler) CleanupExpiredStrips() {
@@ -522,31 +789,14 @@ func (h *Handler) CleanupExpiredStrips() {
 	log.Printf("Found %d expired guest strips to clean up", len(expiredStrips))
 
 	for _, strip := range expiredStrips {
-		// 1. Extract Key from URL
-		// URL Format: https://{bucket}.sgp1.cdn.digitaloceanspaces.com/strips/guest/{uuid}.png
-		parts := strings.SplitN(strip.FileURL, ".com/", 2)
-		if len(parts) < 2 {
-			log.Printf("Cleanup Warning: Could not parse key from URL %s", strip.FileURL)
-			continue
-		}
-		key := parts[1]
+		// 1-2. Delete the image and original shots from S3
+		h.deleteStripObject(context.Background(), strip)
 
-		// 2. Delete from S3
-		_, err := h.S3Client.DeleteObject(context.Background(), &s3.DeleteObjectInput{
-			Bucket: aws.String(h.Bucket),
-			Key:    aws.String(key),
-		})
-		if err != nil {
-			log.Printf("Cleanup Error (S3 Delete %s): %v", key, err)
-			// Continue to DB delete anyway to avoid getting stuck, or omit? 
-			// Usually better to delete DB if S3 is gone or failed 404
-		}
-
-		// 3. Delete from DB
-		if err := h.DB.Delete(&strip).Error; err != nil {
+		// 3. Delete from DB (permanently; expired guest strips skip the trash)
+		if err := h.DB.Unscoped().Delete(&strip).Error; err != nil {
 			log.Printf("Cleanup Error (DB Delete ID %s): %v", strip.ID, err)
 		} else {
-			log.Printf("Successfully cleaned up expired strip: %s", key)
+			log.Printf("Successfully cleaned up expired strip: %s", strip.ID)
 		}
 	}
 }
@@ -554,29 +804,60 @@ func (h *Handler) CleanupExpiredStrips() {
 // ADMIN HANDLERS
 
 func (h *Handler) AdminGetUsers(c *gin.Context) {
+	h.listUsers(c, h.DB.Model(&models.User{}))
+}
+
+// listUsers serves a paginated user listing on top of base.
+func (h *Handler) listUsers(c *gin.Context, base *gorm.DB) {
+	page, err := parsePageRequest(c, userSorts, "created_at", "users.id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	query := applyDateRange(base, "users.created_at", from, to)
+
+	resp := gin.H{}
+	if page.Cursor == nil {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
+			return
+		}
+		resp["total"] = total
+	}
+
+	query, err = page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
 	var users []models.User
-	if err := h.DB.Order("created_at desc").Find(&users).Error; err != nil {
+	if err := query.Find(&users).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch users"})
 		return
 	}
-	c.JSON(http.StatusOK, users)
+
+	users, next := paginate(users, page, userCursorKey(page))
+	resp["users"] = users
+	resp["next_cursor"] = next
+	c.JSON(http.StatusOK, resp)
 }
 
 func (h *Handler) AdminGetStrips(c *gin.Context) {
 	userID := c.Query("user_id")
-	var strips []models.Strip
-	
-	query := h.DB.Order("created_at desc")
-	if userID != "" {
-		query = query.Where("user_id = ?", userID)
-	}
 
-	// Preload User to show who owns it
-	if err := query.Preload("User").Find(&strips).Error; err != nil {
-		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
-		return
+	query := h.DB.Model(&models.Strip{})
+	if userID != "" {
+		query = query.Where("strips.user_id = ?", userID)
 	}
-	c.JSON(http.StatusOK, strips)
+	h.listStrips(c, query, true, true)
 }
 
 func (h *Handler) AdminDeleteUser(c *gin.Context) {
@@ -585,32 +866,28 @@ func (h *Handler) AdminDeleteUser(c *gin.Context) {
 	// Prevent self-deletion if needed, or deleting the superuser if we had a check
 	// For now, just basic delete logic
 
-	// 1. Delete all strips belonging to this user (Cleanup S3)
-	var strips []models.Strip
-	h.DB.Where("user_id = ?", userID).Find(&strips)
-	for _, strip := range strips {
-		if strip.FileURL != "" {
-			u, err := url.Parse(strip.FileURL)
-			if err == nil {
-				key := strings.TrimPrefix(u.Path, "/")
-				if key != "" {
-					h.S3Client.DeleteObject(c.Request.Context(), &s3.DeleteObjectInput{
-						Bucket: aws.String(h.Bucket),
-						Key:    aws.String(key),
-					})
-				}
-			}
+	// Move the user and their strips to the trash with the same timestamp,
+	// so AdminRestoreUser can bring back exactly the strips deleted with them
+	now := time.Now()
+	var found int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Model(&models.User{}).Where("id = ?", userID).Update("deleted_at", now)
+		if res.Error != nil {
+			return res.Error
 		}
-		h.DB.Delete(&strip)
-	}
-
-	// 2. Delete the user
-	if err := h.DB.Delete(&models.User{}, "id = ?", userID).Error; err != nil {
+		found = res.RowsAffected
+		return tx.Model(&models.Strip{}).Where("user_id = ?", userID).Update("deleted_at", now).Error
+	})
+	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete user"})
 		return
 	}
+	if found == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+		return
+	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "User and their memories deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "User and their memories moved to trash"})
 }
 
 func (h *Handler) AdminCreateUser(c *gin.Context) {
@@ -628,27 +905,27 @@ func (h *Handler) AdminCreateUser(c *gin.Context) {
 
 	// Check duplicates
 	var existing models.User
-	if err := h.DB.Where("email = ?", req.Email).First(&existing).Error; err == nil {
+	if err := h.DB.Unscoped().Where("email = ?", req.Email).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Email already in use"})
 		return
 	}
-	if err := h.DB.Where("username = ?", req.Username).First(&existing).Error; err == nil {
+	if err := h.DB.Unscoped().Where("username = ?", req.Username).First(&existing).Error; err == nil {
 		c.JSON(http.StatusConflict, gin.H{"error": "Username already taken"})
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := h.Passwords.Hash(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
 
 	user := models.User{
-		ID:       uuid.New().String(),
-		Username: req.Username,
-		Email:    req.Email,
-		Password: string(hashed),
-		IsAdmin:  req.IsAdmin,
+		ID:        uuid.New().String(),
+		Username:  req.Username,
+		Email:     req.Email,
+		Password:  hashed,
+		IsAdmin:   req.IsAdmin,
 		CreatedAt: time.Now(),
 	}
 
@@ -660,7 +937,7 @@ func (h *Handler) AdminCreateUser(c *gin.Context) {
 	c.JSON(http.StatusCreated, gin.H{
 		"message": "User created successfully",
 		"user": gin.H{
-			"id": user.ID,
+			"id":       user.ID,
 			"username": user.Username,
 			"is_admin": user.IsAdmin,
 		},
@@ -676,27 +953,13 @@ func (h *Handler) AdminDeleteStrip(c *gin.Context) {
 		return
 	}
 
-	// Delete from S3
-	if strip.FileURL != "" {
-		u, err := url.Parse(strip.FileURL)
-		if err == nil {
-			key := strings.TrimPrefix(u.Path, "/")
-			if key != "" {
-				h.S3Client.DeleteObject(c.Request.Context(), &s3.DeleteObjectInput{
-					Bucket: aws.String(h.Bucket),
-					Key:    aws.String(key),
-				})
-			}
-		}
-	}
-
-	// Delete from DB
+	// Move to the trash; storage is removed by PurgeTrash
 	if err := h.DB.Delete(&strip).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to delete strip"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"message": "Strip deleted"})
+	c.JSON(http.StatusOK, gin.H{"message": "Strip moved to trash"})
 }
 
 func (h *Handler) AdminResetPassword(c *gin.Context) {
@@ -716,13 +979,13 @@ func (h *Handler) AdminResetPassword(c *gin.Context) {
 		return
 	}
 
-	hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+	hashed, err := h.Passwords.Hash(req.Password)
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
 		return
 	}
 
-	user.Password = string(hashed)
+	user.Password = hashed
 	if err := h.DB.Save(&user).Error; err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update password"})
 		return
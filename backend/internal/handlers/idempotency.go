@@ -0,0 +1,205 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+	"web-photobooth/backend/internal/models"
+)
+
+const (
+	IdempotencyKeyHeader = "Idempotency-Key"
+	idempotencyKeyTTL    = 24 * time.Hour
+)
+
+// Response fields that are never stored for replay: they are credentials the
+// database otherwise only keeps hashes of.
+var idempotencySecretFields = []string{"claim_token", "manage_token", "manage_url"}
+
+// idempotencyReissue puts fresh credentials into a replayed response whose
+// secret fields were redacted.
+type idempotencyReissue func(resp map[string]interface{}) error
+
+// idempotencyRecorder tees the response body so it can be stored for replay.
+type idempotencyRecorder struct {
+	gin.ResponseWriter
+	body bytes.Buffer
+}
+
+func (w *idempotencyRecorder) Write(b []byte) (int, error) {
+	w.body.Write(b)
+	return w.ResponseWriter.Write(b)
+}
+
+func (w *idempotencyRecorder) WriteString(s string) (int, error) {
+	w.body.WriteString(s)
+	return w.ResponseWriter.WriteString(s)
+}
+
+// Idempotency makes a POST route safe to retry. Requests carrying an
+// Idempotency-Key header are fingerprinted; a retry with the same key and body
+// replays the stored response, while reusing a key with a different body is
+// rejected with 422. Requests without the header are passed through untouched.
+//
+// Secret fields are redacted from the stored response; reissue, if set, mints
+// new ones on replay.
+func (h *Handler) Idempotency(reissue idempotencyReissue) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		key := c.GetHeader(IdempotencyKeyHeader)
+		if key == "" {
+			c.Next()
+			return
+		}
+		if len(key) > 255 {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Idempotency-Key is too long"})
+			return
+		}
+
+		// 1. Fingerprint the request, then restore the body for the handler
+		body, err := io.ReadAll(c.Request.Body)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+			return
+		}
+		c.Request.Body = io.NopCloser(bytes.NewReader(body))
+
+		sum := sha256.New()
+		sum.Write([]byte(c.Request.Method + " " + c.FullPath() + "\n"))
+		sum.Write(body)
+		fingerprint := hex.EncodeToString(sum.Sum(nil))
+
+		// Guests have no identity to scope by, so their keys are scoped by the
+		// client IP; a replay still needs the exact body
+		caller := c.GetString("user_id")
+		if caller == "" {
+			caller = "guest:" + c.ClientIP()
+		}
+		scope := c.FullPath() + ":" + caller
+
+		// 2. Reserve the key; only the first request gets to run the handler
+		record := models.IdempotencyKey{
+			Key:         key,
+			Scope:       scope,
+			Fingerprint: fingerprint,
+			CreatedAt:   time.Now(),
+		}
+		res := h.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&record)
+		if res.Error != nil {
+			log.Printf("Idempotency Error (reserve): %v", res.Error)
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+			return
+		}
+
+		if res.RowsAffected == 0 {
+			var existing models.IdempotencyKey
+			if err := h.DB.First(&existing, "key = ? AND scope = ?", key, scope).Error; err != nil {
+				c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+				return
+			}
+			switch {
+			case existing.Fingerprint != fingerprint:
+				c.AbortWithStatusJSON(http.StatusUnprocessableEntity, gin.H{"error": "Idempotency-Key was already used with a different request"})
+			case !existing.Completed:
+				c.AbortWithStatusJSON(http.StatusConflict, gin.H{"error": "A request with this Idempotency-Key is still in progress"})
+			default:
+				body, err := h.replayBody(existing, reissue)
+				if err != nil {
+					log.Printf("Idempotency Error (replay): %v", err)
+					c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"error": "Failed to process request"})
+					return
+				}
+				c.Header("Idempotent-Replayed", "true")
+				c.Data(existing.StatusCode, "application/json; charset=utf-8", body)
+				c.Abort()
+			}
+			return
+		}
+
+		// 3. Run the handler and store its response. A panic releases the key,
+		// or every retry would get 409 until it expires
+		defer func() {
+			if r := recover(); r != nil {
+				h.DB.Delete(&models.IdempotencyKey{}, "key = ? AND scope = ?", key, scope)
+				panic(r)
+			}
+		}()
+		recorder := &idempotencyRecorder{ResponseWriter: c.Writer}
+		c.Writer = recorder
+		c.Next()
+
+		status := recorder.Status()
+		if status >= http.StatusInternalServerError {
+			// Server errors are retryable, so release the key
+			h.DB.Delete(&models.IdempotencyKey{}, "key = ? AND scope = ?", key, scope)
+			return
+		}
+
+		if err := h.DB.Model(&models.IdempotencyKey{}).
+			Where("key = ? AND scope = ?", key, scope).
+			Updates(map[string]interface{}{
+				"status_code":   status,
+				"response_body": redactSecrets(recorder.body.Bytes()),
+				"completed":     true,
+			}).Error; err != nil {
+			log.Printf("Idempotency Error (store): %v", err)
+		}
+	}
+}
+
+// redactSecrets drops idempotencySecretFields from a JSON object response.
+// Anything else is stored as is.
+func redactSecrets(body []byte) []byte {
+	var resp map[string]interface{}
+	if err := json.Unmarshal(body, &resp); err != nil {
+		return body
+	}
+	redacted := false
+	for _, field := range idempotencySecretFields {
+		if _, ok := resp[field]; ok {
+			delete(resp, field)
+			redacted = true
+		}
+	}
+	if !redacted {
+		return body
+	}
+	out, err := json.Marshal(resp)
+	if err != nil {
+		return nil
+	}
+	return out
+}
+
+// replayBody returns the stored response, with fresh credentials filled in by
+// reissue for successful responses.
+func (h *Handler) replayBody(record models.IdempotencyKey, reissue idempotencyReissue) ([]byte, error) {
+	if reissue == nil || record.StatusCode >= http.StatusBadRequest {
+		return record.ResponseBody, nil
+	}
+	var resp map[string]interface{}
+	if err := json.Unmarshal(record.ResponseBody, &resp); err != nil {
+		return record.ResponseBody, nil
+	}
+	if err := reissue(resp); err != nil {
+		return nil, err
+	}
+	return json.Marshal(resp)
+}
+
+// CleanupIdempotencyKeys removes stored responses past the replay window.
+func (h *Handler) CleanupIdempotencyKeys() {
+	res := h.DB.Where("created_at < ?", time.Now().Add(-idempotencyKeyTTL)).Delete(&models.IdempotencyKey{})
+	if res.Error != nil {
+		log.Printf("Cleanup Error (Idempotency Keys): %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("Cleaned up %d idempotency keys", res.RowsAffected)
+	}
+}
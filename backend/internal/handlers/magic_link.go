@@ -0,0 +1,200 @@
+package handlers
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"log"
+	"math/big"
+	"net/http"
+	"net/mail"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/tokens"
+)
+
+var (
+	usernameUnsafeChars = regexp.MustCompile(`[^a-z0-9._-]+`)
+	errAccountDeleted   = errors.New("account deleted")
+)
+
+func (h *Handler) RequestMagicLink(c *gin.Context) {
+	var req struct {
+		Email string `json:"email"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	email := strings.ToLower(strings.TrimSpace(req.Email))
+	if addr, err := mail.ParseAddress(email); err != nil || addr.Address != email {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A valid email address is required"})
+		return
+	}
+
+	// 1. Rate limit per email address
+	var recent int64
+	if err := h.DB.Model(&models.MagicLink{}).
+		Where("email = ? AND created_at > ?", email, time.Now().Add(-time.Hour)).
+		Count(&recent).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sign-in link"})
+		return
+	}
+	if recent >= int64(h.MagicLinkMaxPerHour) {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many sign-in links requested. Please try again later."})
+		return
+	}
+
+	// 2. Mint the token, store only its hash
+	token, err := tokens.Generate()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sign-in link"})
+		return
+	}
+
+	link := models.MagicLink{
+		ID:        uuid.New().String(),
+		Email:     email,
+		TokenHash: tokens.Hash(token),
+		ExpiresAt: time.Now().Add(h.MagicLinkTTL),
+		CreatedAt: time.Now(),
+	}
+	if err := h.DB.Create(&link).Error; err != nil {
+		log.Printf("RequestMagicLink DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create sign-in link"})
+		return
+	}
+
+	// 3. Email the link
+	signInURL := fmt.Sprintf("%s/auth/magic?token=%s", h.AppURL, url.QueryEscape(token))
+	body := fmt.Sprintf("Tap the link below to sign in to Wuby Photobooth:\n\n%s\n\nThis link expires in %d minutes and can only be used once. If you did not request it, you can ignore this email.",
+		signInURL, int(h.MagicLinkTTL.Minutes()))
+
+	if err := h.Mailer.Send(email, "Your Wuby sign-in link", body); err != nil {
+		log.Printf("RequestMagicLink Mail Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to send sign-in link"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Sign-in link sent"})
+}
+
+func (h *Handler) RedeemMagicLink(c *gin.Context) {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.Token == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+
+	// 1. Consume the link atomically so it can only be used once
+	var link models.MagicLink
+	var user models.User
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		res := tx.Model(&models.MagicLink{}).
+			Where("token_hash = ? AND used_at IS NULL AND expires_at > ?", tokens.Hash(req.Token), now).
+			Update("used_at", now)
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return gorm.ErrRecordNotFound
+		}
+		if err := tx.First(&link, "token_hash = ?", tokens.Hash(req.Token)).Error; err != nil {
+			return err
+		}
+
+		// 2. Find or create the account on first use. A trashed account
+		// still owns its email, so it can't be recreated.
+		err := tx.Unscoped().Where("email = ?", link.Email).First(&user).Error
+		if err == nil {
+			if user.DeletedAt.Valid {
+				return errAccountDeleted
+			}
+			return nil
+		}
+		if !errors.Is(err, gorm.ErrRecordNotFound) {
+			return err
+		}
+
+		username, err := h.uniqueUsernameFor(tx, link.Email)
+		if err != nil {
+			return err
+		}
+		user = models.User{
+			ID:        uuid.New().String(),
+			Username:  username,
+			Email:     link.Email,
+			CreatedAt: time.Now(),
+		}
+		log.Printf("RedeemMagicLink: creating user %s for %s", user.ID, link.Email)
+		return tx.Create(&user).Error
+	})
+
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "This sign-in link is invalid or has expired"})
+		return
+	}
+	if errors.Is(err, errAccountDeleted) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This account has been deleted"})
+		return
+	}
+	if err != nil {
+		log.Printf("RedeemMagicLink Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to sign in"})
+		return
+	}
+
+	h.issueSession(c, user)
+}
+
+// CleanupMagicLinks removes sign-in links that can no longer be redeemed.
+// Links are kept for an hour past expiry so the per-email rate limit holds.
+func (h *Handler) CleanupMagicLinks() {
+	cutoff := time.Now().Add(-time.Hour)
+	res := h.DB.Where("expires_at < ? AND created_at < ?", time.Now(), cutoff).Delete(&models.MagicLink{})
+	if res.Error != nil {
+		log.Printf("Cleanup Error (Magic Links): %v", res.Error)
+	} else if res.RowsAffected > 0 {
+		log.Printf("Cleaned up %d expired magic links", res.RowsAffected)
+	}
+}
+
+// uniqueUsernameFor derives a username from the local part of an email and
+// appends a random suffix until it doesn't collide with an existing account.
+func (h *Handler) uniqueUsernameFor(tx *gorm.DB, email string) (string, error) {
+	base := strings.ToLower(strings.SplitN(email, "@", 2)[0])
+	base = usernameUnsafeChars.ReplaceAllString(base, "")
+	if base == "" {
+		base = "guest"
+	}
+
+	candidate := base
+	for i := 0; i < 10; i++ {
+		var count int64
+		if err := tx.Unscoped().Model(&models.User{}).Where("username = ?", candidate).Count(&count).Error; err != nil {
+			return "", err
+		}
+		if count == 0 {
+			return candidate, nil
+		}
+		n, err := rand.Int(rand.Reader, big.NewInt(10000))
+		if err != nil {
+			return "", err
+		}
+		candidate = fmt.Sprintf("%s%04d", base, n.Int64())
+	}
+	return "", errors.New("could not find a free username")
+}
@@ -0,0 +1,221 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/render"
+)
+
+// Widths GetStripImage scales strips down to. oEmbed consumers get the
+// largest one (or the original) that fits their maxwidth and maxheight.
+var derivativeWidths = []int{160, 320, 480, 640, 960}
+
+const oembedCacheAge = 3600
+
+var sharePagePath = regexp.MustCompile(`^/v/([^/]+)/?$`)
+
+// oembedResponse is a photo-type oEmbed response.
+type oembedResponse struct {
+	XMLName      xml.Name `json:"-" xml:"oembed"`
+	Version      string   `json:"version" xml:"version"`
+	Type         string   `json:"type" xml:"type"`
+	ProviderName string   `json:"provider_name" xml:"provider_name"`
+	ProviderURL  string   `json:"provider_url" xml:"provider_url"`
+	Title        string   `json:"title" xml:"title"`
+	AuthorName   string   `json:"author_name,omitempty" xml:"author_name,omitempty"`
+	CacheAge     int      `json:"cache_age" xml:"cache_age"`
+	URL          string   `json:"url" xml:"url"`
+	Width        int      `json:"width" xml:"width"`
+	Height       int      `json:"height" xml:"height"`
+}
+
+// OEmbed describes a share page (/v/<id>) as an oEmbed photo. ?format= is
+// json (default) or xml. Private strips get 401; missing and expired ones
+// get 404, as the spec asks.
+func (h *Handler) OEmbed(c *gin.Context) {
+	format := c.DefaultQuery("format", "json")
+	if format != "json" && format != "xml" {
+		c.JSON(http.StatusNotImplemented, gin.H{"error": "format must be json or xml"})
+		return
+	}
+
+	maxWidth, err := oembedMax(c, "maxwidth")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	maxHeight, err := oembedMax(c, "maxheight")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	// 1. Only our own share pages
+	id, ok := h.sharePageID(c.Query("url"))
+	if !ok {
+		c.JSON(http.StatusNotFound, gin.H{"error": "URL is not a Wuby memory"})
+		return
+	}
+
+	strip, status, msg := h.lookupPublicStrip(c, id)
+	if status == http.StatusNotFound {
+		var private int64
+		stripByPublicID(h.DB.Model(&models.Strip{}), id).Where("visibility = ?", models.VisibilityPrivate).Count(&private)
+		if private > 0 {
+			status, msg = http.StatusUnauthorized, "This memory is private"
+		}
+	}
+	if status == http.StatusGone {
+		status = http.StatusNotFound
+	}
+	if status != http.StatusOK {
+		c.JSON(status, gin.H{"error": msg})
+		return
+	}
+
+	// 2. The original's size, from the image header only
+	key := storageKey(strip.FileURL)
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory has no image"})
+		return
+	}
+	obj, err := h.openObject(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("OEmbed Storage Error: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read from storage"})
+		return
+	}
+	cfg, _, err := image.DecodeConfig(obj.Body)
+	obj.Body.Close()
+	if err != nil {
+		log.Printf("OEmbed Decode Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to read image"})
+		return
+	}
+
+	// 3. The largest size that fits
+	resp := oembedResponse{
+		Version:      "1.0",
+		Type:         "photo",
+		ProviderName: "Wuby",
+		ProviderURL:  h.AppURL,
+		Title:        strip.Title,
+		CacheAge:     oembedCacheAge,
+	}
+	if resp.Title == "" {
+		resp.Title = ogDefaultTitle
+	}
+	if !fitsWithin(cfg.Width, cfg.Height, maxWidth, maxHeight) {
+		for _, w := range slices.Backward(derivativeWidths) {
+			if dh := derivativeHeight(cfg.Width, cfg.Height, w); w < cfg.Width && fitsWithin(w, dh, maxWidth, maxHeight) {
+				resp.URL, resp.Width, resp.Height = fmt.Sprintf("%s/api/strips/%s/image?w=%d", h.AppURL, strip.ID, w), w, dh
+				break
+			}
+		}
+		if resp.URL == "" {
+			c.JSON(http.StatusNotFound, gin.H{"error": "No image size fits maxwidth and maxheight"})
+			return
+		}
+	} else {
+		resp.URL, resp.Width, resp.Height = strip.FileURL, cfg.Width, cfg.Height
+	}
+
+	// 4. Guest strips have no author
+	if strip.UserID != nil {
+		var owner models.User
+		if err := h.DB.Select("username").First(&owner, "id = ?", *strip.UserID).Error; err == nil {
+			resp.AuthorName = owner.Username
+		}
+	}
+
+	c.Header("Cache-Control", fmt.Sprintf("public, max-age=%d", oembedCacheAge))
+	if format == "json" {
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+	body, err := xml.Marshal(resp)
+	if err != nil {
+		log.Printf("OEmbed XML Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Internal error"})
+		return
+	}
+	c.Data(http.StatusOK, "text/xml; charset=utf-8", append([]byte(`<?xml version="1.0" encoding="utf-8" standalone="yes"?>`+"\n"), body...))
+}
+
+// sharePageID pulls the strip ID out of a share page URL on the app's or the
+// share domain.
+func (h *Handler) sharePageID(raw string) (string, bool) {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return "", false
+	}
+	known := false
+	for _, base := range []string{h.AppURL, h.ShareBaseURL} {
+		if b, err := url.Parse(base); err == nil && strings.EqualFold(b.Host, u.Host) {
+			known = true
+		}
+	}
+	m := sharePagePath.FindStringSubmatch(u.Path)
+	if !known || m == nil {
+		return "", false
+	}
+	return m[1], true
+}
+
+// oembedMax reads maxwidth or maxheight; 0 means no limit.
+func oembedMax(c *gin.Context, name string) (int, error) {
+	raw := c.Query(name)
+	if raw == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, errors.New(name + " must be a positive integer")
+	}
+	return n, nil
+}
+
+func fitsWithin(w, h, maxWidth, maxHeight int) bool {
+	return (maxWidth == 0 || w <= maxWidth) && (maxHeight == 0 || h <= maxHeight)
+}
+
+func derivativeHeight(width, height, w int) int {
+	return max(1, (height*w+width/2)/width)
+}
+
+// GetStripImage redirects to a strip's image scaled down to ?w=, one of
+// derivativeWidths. Each width is rendered once, on first request; strips
+// narrower than that are served at their own size. Access follows
+// GetPublicStrip.
+func (h *Handler) GetStripImage(c *gin.Context) {
+	w, err := strconv.Atoi(c.Query("w"))
+	if err != nil || !slices.Contains(derivativeWidths, w) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("w must be one of %v", derivativeWidths)})
+		return
+	}
+
+	strip, ok := h.publicStrip(c)
+	if !ok {
+		return
+	}
+
+	// Scale down, never up
+	h.serveRendition(c, "GetStripImage", strip, fmt.Sprintf("w%d", w), func(img image.Image) (image.Image, error) {
+		if w < img.Bounds().Dx() {
+			img = render.Resize(img, w)
+		}
+		return img, nil
+	})
+}
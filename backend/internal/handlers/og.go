@@ -0,0 +1,169 @@
+package handlers
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"html/template"
+	"image"
+	"log"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/render"
+)
+
+const (
+	ogDefaultTitle       = "A Wuby memory"
+	ogDefaultDescription = "View this digital photobooth memory captured with Wuby."
+)
+
+// GetStripOGImage redirects to the 1200x630 social card for a strip,
+// rendering it on first request. Access follows GetPublicStrip.
+func (h *Handler) GetStripOGImage(c *gin.Context) {
+	strip, ok := h.publicStrip(c)
+	if !ok {
+		return
+	}
+
+	// The card shows the title, so a renamed strip needs a new one
+	sum := sha256.Sum256([]byte(strip.Title))
+	name := "og-" + hex.EncodeToString(sum[:6])
+
+	h.serveRendition(c, "GetStripOGImage", strip, name, func(img image.Image) (image.Image, error) {
+		return render.OGCard(img, strip.Title)
+	})
+}
+
+// serveRendition redirects to the cached rendition name of the strip's
+// current image. The first request renders it from the image and stores it
+// next to it; replacing the image deletes it again.
+func (h *Handler) serveRendition(c *gin.Context, op string, strip models.Strip, name string, draw func(image.Image) (image.Image, error)) {
+	ctx := c.Request.Context()
+
+	// 1. Already stored?
+	key := storageKey(strip.FileURL)
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory has no image"})
+		return
+	}
+	derivedKey := derivedObjectKey(key, name)
+	exists, err := h.objectExists(ctx, derivedKey)
+	if err != nil {
+		log.Printf("%s Storage Error: %v", op, err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read from storage"})
+		return
+	}
+
+	// 2. Render and store it once
+	if !exists {
+		data, err := h.getObject(ctx, key)
+		if err != nil {
+			log.Printf("%s Storage Error: %v", op, err)
+			c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read from storage"})
+			return
+		}
+		img, err := decodeImageLimited(bytes.NewReader(data))
+		if err == nil {
+			img, err = draw(img)
+		}
+		if err == nil {
+			data, err = render.EncodePNG(img)
+		}
+		if err != nil {
+			log.Printf("%s Render Error: %v", op, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render image"})
+			return
+		}
+		if _, err := h.uploadObject(ctx, derivedKey, data, "image/png"); err != nil {
+			log.Printf("%s Upload Error: %v", op, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+			return
+		}
+	}
+
+	// An hour keeps a replaced image showing up soon enough
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Redirect(http.StatusFound, h.cdnURL(derivedKey))
+}
+
+var stripMetaTemplate = template.Must(template.New("meta").Parse(`<!doctype html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{.Title}}</title>
+<meta name="description" content="{{.Description}}">
+{{if .Found}}<meta name="robots" content="noindex">
+<link rel="canonical" href="{{.URL}}">
+<meta property="og:type" content="website">
+<meta property="og:site_name" content="Wuby">
+<meta property="og:title" content="{{.Title}}">
+<meta property="og:description" content="{{.Description}}">
+<meta property="og:url" content="{{.URL}}">
+<meta property="og:image" content="{{.Image}}">
+<meta property="og:image:type" content="image/png">
+<meta property="og:image:width" content="{{.Width}}">
+<meta property="og:image:height" content="{{.Height}}">
+<meta name="twitter:card" content="summary_large_image">
+<meta name="twitter:title" content="{{.Title}}">
+<meta name="twitter:description" content="{{.Description}}">
+<meta name="twitter:image" content="{{.Image}}">
+<link rel="alternate" type="application/json+oembed" href="{{.OEmbed}}&format=json" title="{{.Title}}">
+<link rel="alternate" type="text/xml+oembed" href="{{.OEmbed}}&format=xml" title="{{.Title}}">
+{{else}}<meta name="robots" content="noindex">
+{{end}}</head>
+<body>
+{{if .Found}}<a href="{{.URL}}">{{.Title}}</a>{{else}}<p>{{.Title}}</p>{{end}}
+</body>
+</html>
+`))
+
+type stripMeta struct {
+	Found         bool
+	Title         string
+	Description   string
+	URL           string
+	Image         string
+	OEmbed        string
+	Width, Height int
+}
+
+// GetStripMeta serves minimal HTML with Open Graph and Twitter tags for link
+// previews. The SPA renders /v/<id> client-side, so nginx sends crawlers here
+// instead. Private, missing and expired strips get a bare page with the
+// matching status and nothing about the strip.
+func (h *Handler) GetStripMeta(c *gin.Context) {
+	strip, status, msg := h.lookupPublicStrip(c, c.Param("id"))
+
+	meta := stripMeta{Title: msg, Description: ogDefaultDescription}
+	if status == http.StatusOK {
+		meta = stripMeta{
+			Found:       true,
+			Title:       strip.Title,
+			Description: strip.Caption,
+			URL:         h.stripShareURL(strip),
+			Image:       fmt.Sprintf("%s/api/strips/%s/og.png", h.AppURL, stripPublicID(strip)),
+			OEmbed:      fmt.Sprintf("%s/api/oembed?url=%s", h.AppURL, url.QueryEscape(h.stripShareURL(strip))),
+			Width:       render.OGWidth,
+			Height:      render.OGHeight,
+		}
+		if meta.Title == "" {
+			meta.Title = ogDefaultTitle
+		}
+		if meta.Description == "" {
+			meta.Description = ogDefaultDescription
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := stripMetaTemplate.Execute(&buf, meta); err != nil {
+		log.Printf("GetStripMeta Template Error: %v", err)
+		c.String(http.StatusInternalServerError, "Internal error")
+		return
+	}
+	c.Header("Cache-Control", "public, max-age=600")
+	c.Data(status, "text/html; charset=utf-8", buf.Bytes())
+}
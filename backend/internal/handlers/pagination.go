@@ -0,0 +1,193 @@
+package handlers
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+const (
+	defaultPageLimit = 50
+	maxPageLimit     = 200
+)
+
+// sortColumn is a sortable, table-qualified column or SQL expression (with
+// Args bound to its placeholders). Time columns are carried through the cursor
+// as RFC 3339 strings, float columns as exact decimal strings.
+type sortColumn struct {
+	Column  string
+	Args    []interface{}
+	IsTime  bool
+	IsFloat bool
+}
+
+// pageCursor marks the last row of a page: its sort value and its ID, which
+// breaks ties so keyset pagination never skips or repeats rows.
+type pageCursor struct {
+	Value string `json:"v"`
+	ID    string `json:"id"`
+}
+
+type pageRequest struct {
+	Limit    int
+	SortKey  string
+	Sort     sortColumn
+	IDColumn string
+	Desc     bool
+	Cursor   *pageCursor
+}
+
+// parsePageRequest reads limit, sort, order and cursor from the query string.
+// The default order is newest first for time columns, highest first for
+// scores, and A-Z otherwise.
+func parsePageRequest(c *gin.Context, sorts map[string]sortColumn, defaultSort, idColumn string) (pageRequest, error) {
+	p := pageRequest{Limit: defaultPageLimit, IDColumn: idColumn}
+
+	if raw := c.Query("limit"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 1 {
+			return p, errors.New("limit must be a positive integer")
+		}
+		p.Limit = min(n, maxPageLimit)
+	}
+
+	sortKey := c.DefaultQuery("sort", defaultSort)
+	sort, ok := sorts[sortKey]
+	if !ok {
+		return p, fmt.Errorf("unsupported sort %q", sortKey)
+	}
+	p.SortKey = sortKey
+	p.Sort = sort
+	p.Desc = sort.IsTime || sort.IsFloat
+
+	switch strings.ToLower(c.Query("order")) {
+	case "":
+	case "asc":
+		p.Desc = false
+	case "desc":
+		p.Desc = true
+	default:
+		return p, errors.New("order must be asc or desc")
+	}
+
+	if raw := c.Query("cursor"); raw != "" {
+		data, err := base64.RawURLEncoding.DecodeString(raw)
+		if err != nil {
+			return p, errors.New("invalid cursor")
+		}
+		var cur pageCursor
+		if err := json.Unmarshal(data, &cur); err != nil || cur.ID == "" {
+			return p, errors.New("invalid cursor")
+		}
+		p.Cursor = &cur
+	}
+
+	return p, nil
+}
+
+// apply adds the keyset condition, ordering and limit. One extra row is
+// fetched so the caller can tell whether another page exists.
+func (p pageRequest) apply(q *gorm.DB) (*gorm.DB, error) {
+	dir, cmp := "ASC", ">"
+	if p.Desc {
+		dir, cmp = "DESC", "<"
+	}
+
+	if p.Cursor != nil {
+		var value interface{} = p.Cursor.Value
+		switch {
+		case p.Sort.IsTime:
+			t, err := time.Parse(time.RFC3339Nano, p.Cursor.Value)
+			if err != nil {
+				return nil, errors.New("invalid cursor")
+			}
+			value = t
+		case p.Sort.IsFloat:
+			f, err := strconv.ParseFloat(p.Cursor.Value, 64)
+			if err != nil {
+				return nil, errors.New("invalid cursor")
+			}
+			value = f
+		}
+		args := append(append([]interface{}{}, p.Sort.Args...), value, p.Cursor.ID)
+		q = q.Where(fmt.Sprintf("(%s, %s) %s (?, ?)", p.Sort.Column, p.IDColumn, cmp), args...)
+	}
+
+	return q.Order(clause.OrderBy{Expression: clause.Expr{
+		SQL:                fmt.Sprintf("%s %s, %s %s", p.Sort.Column, dir, p.IDColumn, dir),
+		Vars:               p.Sort.Args,
+		WithoutParentheses: true,
+	}}).Limit(p.Limit + 1), nil
+}
+
+// paginate trims the extra row fetched by apply and builds the next cursor
+// from the last returned item. key returns the item's value for the active
+// sort (see cursorTime) and its ID.
+func paginate[T any](items []T, p pageRequest, key func(T) (string, string)) ([]T, string) {
+	if len(items) <= p.Limit {
+		return items, ""
+	}
+	items = items[:p.Limit]
+
+	value, id := key(items[len(items)-1])
+	data, _ := json.Marshal(pageCursor{Value: value, ID: id})
+	return items, base64.RawURLEncoding.EncodeToString(data)
+}
+
+// cursorTime formats a time sort value the way apply parses it back.
+func cursorTime(t time.Time) string {
+	return t.UTC().Format(time.RFC3339Nano)
+}
+
+// cursorFloat formats a float sort value so that it parses back exactly.
+func cursorFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
+
+// parseDateRange reads the optional from/to query parameters. Both accept
+// RFC 3339 timestamps or plain dates; a plain "to" date includes the whole day.
+func parseDateRange(c *gin.Context) (from, to *time.Time, err error) {
+	parse := func(raw string, endOfDay bool) (*time.Time, error) {
+		if raw == "" {
+			return nil, nil
+		}
+		if t, err := time.Parse(time.RFC3339, raw); err == nil {
+			return &t, nil
+		}
+		t, err := time.Parse("2006-01-02", raw)
+		if err != nil {
+			return nil, fmt.Errorf("invalid date %q", raw)
+		}
+		if endOfDay {
+			t = t.AddDate(0, 0, 1)
+		}
+		return &t, nil
+	}
+
+	if from, err = parse(c.Query("from"), false); err != nil {
+		return nil, nil, err
+	}
+	if to, err = parse(c.Query("to"), true); err != nil {
+		return nil, nil, err
+	}
+	return from, to, nil
+}
+
+// applyDateRange restricts a time column to [from, to).
+func applyDateRange(q *gorm.DB, column string, from, to *time.Time) *gorm.DB {
+	if from != nil {
+		q = q.Where(column+" >= ?", *from)
+	}
+	if to != nil {
+		q = q.Where(column+" < ?", *to)
+	}
+	return q
+}
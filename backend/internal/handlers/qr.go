@@ -0,0 +1,110 @@
+package handlers
+
+import (
+	"fmt"
+	"image"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/render"
+)
+
+const (
+	defaultQRSize = 256
+	minQRSize     = 64
+	maxQRSize     = 2048
+	maxQRMargin   = 16
+)
+
+// stripShareURL is the public link to a strip, preferring its short code.
+func (h *Handler) stripShareURL(strip models.Strip) string {
+	return fmt.Sprintf("%s/v/%s", h.ShareBaseURL, stripPublicID(strip))
+}
+
+// stripPublicID is how public URLs name a strip: its short code if it has one.
+func stripPublicID(strip models.Strip) string {
+	if strip.ShortCode != nil {
+		return *strip.ShortCode
+	}
+	return strip.ID
+}
+
+// GetStripQR returns a QR code for the strip's share link. Query options:
+// format (png|svg), size (pixels), margin (modules), ec (L|M|Q|H, default M,
+// or H with a logo) and logo (true to embed the Wuby logo). Access follows
+// GetPublicStrip, except that owners can also get one for a private strip.
+func (h *Handler) GetStripQR(c *gin.Context) {
+	// 1. Options
+	format := strings.ToLower(c.DefaultQuery("format", "png"))
+	if format != "png" && format != "svg" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "format must be png or svg"})
+		return
+	}
+	size, err := strconv.Atoi(c.DefaultQuery("size", strconv.Itoa(defaultQRSize)))
+	if err != nil || size < minQRSize || size > maxQRSize {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("size must be between %d and %d", minQRSize, maxQRSize)})
+		return
+	}
+	margin, err := strconv.Atoi(c.DefaultQuery("margin", "4"))
+	if err != nil || margin < 0 || margin > maxQRMargin {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("margin must be between 0 and %d", maxQRMargin)})
+		return
+	}
+	logo, err := strconv.ParseBool(c.DefaultQuery("logo", "false"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "logo must be true or false"})
+		return
+	}
+	level := strings.ToUpper(c.Query("ec"))
+	if level == "" {
+		level = "M"
+		if logo {
+			level = "H"
+		}
+	}
+	switch level {
+	case "L", "M":
+		if logo {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "logo needs ec Q or H"})
+			return
+		}
+	case "Q", "H":
+	default:
+		c.JSON(http.StatusBadRequest, gin.H{"error": "ec must be L, M, Q or H"})
+		return
+	}
+
+	strip, ok := h.viewableStrip(c)
+	if !ok {
+		return
+	}
+
+	// 2. Draw
+	opts := render.QROptions{Size: size, Margin: margin, Level: level, Logo: logo}
+	content := h.stripShareURL(strip)
+
+	var body []byte
+	contentType := "image/png"
+	if format == "svg" {
+		contentType = "image/svg+xml"
+		body, err = render.QRSVG(content, opts)
+	} else {
+		var img image.Image
+		if img, err = render.QR(content, opts); err == nil {
+			body, err = render.EncodePNG(img)
+		}
+	}
+	if err != nil {
+		log.Printf("GetStripQR Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to generate QR code"})
+		return
+	}
+
+	// The link only changes if an admin regenerates the short code
+	c.Header("Cache-Control", "public, max-age=3600")
+	c.Data(http.StatusOK, contentType, body)
+}
@@ -0,0 +1,94 @@
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"regexp"
+	"unicode/utf8"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+)
+
+// These mirror the options offered by the photobooth preview page
+// (web/src/routes/photobooth/preview/settings.ts).
+var (
+	knownFilters = map[string]bool{
+		"none": true, "cinematic": true, "film": true, "warm": true, "bw": true,
+	}
+	knownFonts = map[string]bool{
+		"Lobster": true, "Pacifico": true, "Caveat": true, "Dancing Script": true,
+		"Bebas Neue": true, "Righteous": true, "Abril Fatface": true,
+		"Cormorant Garamond": true, "Permanent Marker": true, "Special Elite": true,
+		"Monoton": true, "Montserrat": true,
+	}
+	hexColor = regexp.MustCompile(`^#[0-9a-fA-F]{6}$`)
+)
+
+const (
+	minCaptionSize   = 10
+	maxCaptionSize   = 100
+	maxCaptionLength = 200
+	minStripPhotos   = 2
+	maxStripPhotos   = 4
+)
+
+// validateRenderSettings checks settings against the options the frontend
+// can actually produce.
+func validateRenderSettings(s *models.RenderSettings) error {
+	if !knownFilters[s.Filter] {
+		return fmt.Errorf("unknown filter %q", s.Filter)
+	}
+	if !knownFonts[s.Font] {
+		return fmt.Errorf("unknown font %q", s.Font)
+	}
+	if !hexColor.MatchString(s.StripColor) {
+		return errors.New("strip_color must be a #rrggbb color")
+	}
+	if s.CaptionSize < minCaptionSize || s.CaptionSize > maxCaptionSize {
+		return fmt.Errorf("caption_size must be between %d and %d", minCaptionSize, maxCaptionSize)
+	}
+	if utf8.RuneCountInString(s.Caption) > maxCaptionLength {
+		return fmt.Errorf("caption must be at most %d characters", maxCaptionLength)
+	}
+	if s.PhotoCount < minStripPhotos || s.PhotoCount > maxStripPhotos {
+		return fmt.Errorf("photo_count must be between %d and %d", minStripPhotos, maxStripPhotos)
+	}
+	return nil
+}
+
+// styleCount is one row of the style report.
+type styleCount struct {
+	Value string `json:"value"`
+	Count int64  `json:"count"`
+}
+
+// AdminGetStyleStats reports how often each filter, font, strip color and
+// photo count is used across strips that recorded their render settings.
+func (h *Handler) AdminGetStyleStats(c *gin.Context) {
+	resp := gin.H{}
+	for key, field := range map[string]string{
+		"filters":      "filter",
+		"fonts":        "font",
+		"strip_colors": "strip_color",
+		"photo_counts": "photo_count",
+	} {
+		rows := []styleCount{}
+		if err := h.DB.Model(&models.Strip{}).
+			Select("strips.render_settings->>? AS value, COUNT(*) AS count", field).
+			Where("strips.render_settings IS NOT NULL").
+			Group("value").
+			Order("count DESC").
+			Limit(20).
+			Scan(&rows).Error; err != nil {
+			log.Printf("AdminGetStyleStats DB Error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch style stats"})
+			return
+		}
+		resp[key] = rows
+	}
+
+	c.JSON(http.StatusOK, resp)
+}
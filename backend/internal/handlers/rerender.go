@@ -0,0 +1,180 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg" // Shots from the camera are usually JPEG
+	_ "image/png"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/render"
+)
+
+// shotExtensions maps the accepted shot formats to their file extensions.
+var shotExtensions = map[string]string{
+	"image/jpeg": "jpg",
+	"image/png":  "png",
+}
+
+// decodeShots decodes the original shots sent with a save. Each must be a
+// JPEG or PNG within maxImagePixels, and there must be as many as a strip
+// can hold.
+func decodeShots(shots []string) ([][]byte, error) {
+	if len(shots) < minStripPhotos || len(shots) > maxStripPhotos {
+		return nil, fmt.Errorf("shots must contain between %d and %d images", minStripPhotos, maxStripPhotos)
+	}
+
+	decoded := make([][]byte, 0, len(shots))
+	for i, shot := range shots {
+		data, err := decodeImage(shot)
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode shot %d", i+1)
+		}
+		if _, ok := shotExtensions[http.DetectContentType(data)]; !ok {
+			return nil, fmt.Errorf("shot %d must be a JPEG or PNG image", i+1)
+		}
+		cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("failed to decode shot %d", i+1)
+		}
+		if err := checkImageSize(cfg); err != nil {
+			return nil, fmt.Errorf("shot %d is too large", i+1)
+		}
+		decoded = append(decoded, data)
+	}
+	return decoded, nil
+}
+
+// storeShots uploads the shots as private objects under the strip's prefix.
+// If any upload fails, the ones already stored are removed again.
+func (h *Handler) storeShots(ctx context.Context, ownerID, stripID string, shots [][]byte) (models.StringList, error) {
+	var keys models.StringList
+	for i, data := range shots {
+		contentType := http.DetectContentType(data)
+		key := shotObjectKey(ownerID, stripID, i, shotExtensions[contentType])
+		if err := h.uploadPrivateObject(ctx, key, data, contentType); err != nil {
+			for _, k := range keys {
+				h.deleteObject(context.Background(), k)
+			}
+			return nil, err
+		}
+		keys = append(keys, key)
+	}
+	return keys, nil
+}
+
+// RerenderStrip re-renders a strip the caller owns with new settings.
+func (h *Handler) RerenderStrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), userID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	h.rerenderStrip(c, strip)
+}
+
+// GuestRerenderStrip is RerenderStrip for unclaimed guest strips.
+func (h *Handler) GuestRerenderStrip(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+
+	h.rerenderStrip(c, strip)
+}
+
+// rerenderStrip draws the strip again from its stored shots and swaps the
+// result in as the strip's image. Nothing is uploaded unless the render
+// succeeds, and the swap itself rolls back if the DB update fails.
+func (h *Handler) rerenderStrip(c *gin.Context, strip models.Strip) {
+	var req struct {
+		RenderSettings *models.RenderSettings `json:"render_settings"`
+		Timezone       string                 `json:"timezone"` // IANA name for the timestamp, defaults to UTC
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || req.RenderSettings == nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "render_settings is required"})
+		return
+	}
+	settings := *req.RenderSettings
+	if err := validateRenderSettings(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	loc := time.UTC
+	if req.Timezone != "" {
+		l, err := time.LoadLocation(req.Timezone)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Unknown timezone"})
+			return
+		}
+		loc = l
+	}
+
+	// 1. The strip must have kept its originals
+	if len(strip.ShotKeys) == 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This strip has no stored shots to re-render from"})
+		return
+	}
+	if settings.PhotoCount != len(strip.ShotKeys) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": fmt.Sprintf("photo_count must be %d for this strip", len(strip.ShotKeys))})
+		return
+	}
+
+	// 2. Load the shots
+	shots, err := h.loadShots(c.Request.Context(), strip.ShotKeys)
+	if err != nil {
+		log.Printf("RerenderStrip Load Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to load the original shots"})
+		return
+	}
+
+	// 3. Render and encode before touching storage
+	img, err := render.Strip(shots, settings, render.Options{
+		ShareURL:  h.stripShareURL(strip),
+		Timestamp: strip.CreatedAt.In(loc),
+	})
+	if err != nil {
+		log.Printf("RerenderStrip Render Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render strip"})
+		return
+	}
+	imgBytes, err := render.EncodePNG(img)
+	if err != nil {
+		log.Printf("RerenderStrip Encode Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to render strip"})
+		return
+	}
+
+	// 4. Swap the image and record the settings it was drawn with
+	h.replaceStripImage(c, strip, imgBytes, map[string]interface{}{
+		"render_settings": settings,
+		"caption":         settings.Caption,
+	}, false)
+}
+
+func (h *Handler) loadShots(ctx context.Context, keys []string) ([]image.Image, error) {
+	shots := make([]image.Image, 0, len(keys))
+	for _, key := range keys {
+		data, err := h.getObject(ctx, key)
+		if err != nil {
+			return nil, fmt.Errorf("get %s: %w", key, err)
+		}
+		img, err := decodeImageLimited(bytes.NewReader(data))
+		if err != nil {
+			return nil, fmt.Errorf("decode %s: %w", key, err)
+		}
+		shots = append(shots, img)
+	}
+	return shots, nil
+}
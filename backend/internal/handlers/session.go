@@ -0,0 +1,72 @@
+package handlers
+
+import (
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"web-photobooth/backend/internal/config"
+	"web-photobooth/backend/internal/middleware"
+	"web-photobooth/backend/internal/tokens"
+)
+
+const sessionTTL = 72 * time.Hour
+
+type CookieSettings struct {
+	Enabled  bool
+	Domain   string
+	Secure   bool
+	SameSite http.SameSite
+}
+
+func newCookieSettings(cfg *config.Config) CookieSettings {
+	sameSite := http.SameSiteLaxMode
+	switch strings.ToLower(cfg.CookieSameSite) {
+	case "strict":
+		sameSite = http.SameSiteStrictMode
+	case "none":
+		sameSite = http.SameSiteNoneMode
+	}
+
+	return CookieSettings{
+		Enabled:  cfg.CookieAuth,
+		Domain:   cfg.CookieDomain,
+		Secure:   cfg.CookieSecure,
+		SameSite: sameSite,
+	}
+}
+
+// setSessionCookies stores the JWT in an HttpOnly cookie and issues a fresh
+// CSRF token in a script-readable cookie for the double-submit check.
+func (h *Handler) setSessionCookies(c *gin.Context, jwtToken string) (string, error) {
+	csrfToken, err := tokens.Generate()
+	if err != nil {
+		return "", err
+	}
+
+	maxAge := int(sessionTTL.Seconds())
+	http.SetCookie(c.Writer, h.cookie(middleware.SessionCookie, jwtToken, maxAge, true))
+	http.SetCookie(c.Writer, h.cookie(middleware.CSRFCookie, csrfToken, maxAge, false))
+	return csrfToken, nil
+}
+
+func (h *Handler) cookie(name, value string, maxAge int, httpOnly bool) *http.Cookie {
+	return &http.Cookie{
+		Name:     name,
+		Value:    value,
+		Path:     "/",
+		Domain:   h.Cookies.Domain,
+		MaxAge:   maxAge,
+		Secure:   h.Cookies.Secure,
+		HttpOnly: httpOnly,
+		SameSite: h.Cookies.SameSite,
+	}
+}
+
+func (h *Handler) Logout(c *gin.Context) {
+	// Expire both cookies; harmless when cookie mode is off
+	http.SetCookie(c.Writer, h.cookie(middleware.SessionCookie, "", -1, true))
+	http.SetCookie(c.Writer, h.cookie(middleware.CSRFCookie, "", -1, false))
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
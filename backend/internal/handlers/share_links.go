@@ -0,0 +1,332 @@
+package handlers
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"log"
+	"mime"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"golang.org/x/crypto/bcrypt"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/tokens"
+)
+
+// ShareLinkPasswordHeader carries the password for a protected share link.
+const ShareLinkPasswordHeader = "X-Share-Password"
+
+const (
+	shareLinkSlugLength    = 12
+	shareImageTTL          = 30 * time.Minute
+	shareLinkMaxAttempts   = 5
+	shareLinkAttemptWindow = 15 * time.Minute
+)
+
+// shareLinkView is a share link as shown to its owner.
+type shareLinkView struct {
+	models.ShareLink
+	HasPassword bool   `json:"has_password"`
+	URL         string `json:"url"`
+}
+
+func (h *Handler) newShareLinkView(link models.ShareLink) shareLinkView {
+	return shareLinkView{
+		ShareLink:   link,
+		HasPassword: link.PasswordHash != "",
+		URL:         fmt.Sprintf("%s/s/%s", h.AppURL, link.Slug),
+	}
+}
+
+func (h *Handler) CreateShareLink(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+
+	var req struct {
+		Password  string     `json:"password"`
+		ExpiresAt *time.Time `json:"expires_at"`
+		MaxViews  *int       `json:"max_views"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return
+	}
+	if req.ExpiresAt != nil && !req.ExpiresAt.After(time.Now()) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "expires_at must be in the future"})
+		return
+	}
+	if req.MaxViews != nil && *req.MaxViews < 1 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "max_views must be at least 1"})
+		return
+	}
+
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", stripID, userID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	slug, err := tokens.Slug(shareLinkSlugLength)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	link := models.ShareLink{
+		ID:        uuid.New().String(),
+		StripID:   strip.ID,
+		UserID:    userID,
+		Slug:      slug,
+		ExpiresAt: req.ExpiresAt,
+		MaxViews:  req.MaxViews,
+		CreatedAt: time.Now(),
+	}
+
+	if req.Password != "" {
+		hashed, err := bcrypt.GenerateFromPassword([]byte(req.Password), bcrypt.DefaultCost)
+		if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Password is too long"})
+			return
+		}
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to hash password"})
+			return
+		}
+		link.PasswordHash = string(hashed)
+	}
+
+	if err := h.DB.Create(&link).Error; err != nil {
+		log.Printf("CreateShareLink DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create share link"})
+		return
+	}
+
+	c.JSON(http.StatusCreated, h.newShareLinkView(link))
+}
+
+func (h *Handler) ListShareLinks(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+
+	var links []models.ShareLink
+	if err := h.DB.Where("strip_id = ? AND user_id = ?", stripID, userID).
+		Order("created_at DESC").
+		Find(&links).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch share links"})
+		return
+	}
+
+	views := make([]shareLinkView, 0, len(links))
+	for _, link := range links {
+		views = append(views, h.newShareLinkView(link))
+	}
+	c.JSON(http.StatusOK, gin.H{"share_links": views})
+}
+
+// RevokeShareLink stops a link from resolving. The row is kept so the owner
+// can still see how often it was used.
+func (h *Handler) RevokeShareLink(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	res := h.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND strip_id = ? AND user_id = ? AND revoked_at IS NULL", c.Param("linkId"), c.Param("id"), userID).
+		Update("revoked_at", time.Now())
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to revoke share link"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Share link not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Share link revoked"})
+}
+
+// sharedStrip is what a share link reveals: enough to show the strip, but
+// no IDs or storage URLs that would keep working after the link stops.
+type sharedStrip struct {
+	Title          string    `json:"title"`
+	Caption        string    `json:"caption"`
+	CreatedAt      time.Time `json:"created_at"`
+	ImageURL       string    `json:"image_url"`
+	ImageExpiresAt time.Time `json:"image_expires_at"`
+}
+
+// ResolveShareLink shows the linked strip, enforcing the link's revocation,
+// expiry, password and view limit. The image is served by GetShareLinkImage
+// under a short-lived signed URL. Only successful resolutions count as views.
+func (h *Handler) ResolveShareLink(c *gin.Context) {
+	link, ok := h.activeShareLink(c)
+	if !ok {
+		return
+	}
+
+	// 1. View limit
+	if link.MaxViews != nil && link.ViewCount >= *link.MaxViews {
+		c.JSON(http.StatusGone, gin.H{"error": "This link has reached its view limit"})
+		return
+	}
+
+	// 2. Password
+	if link.PasswordHash != "" && !h.checkShareLinkPassword(c, link) {
+		return
+	}
+
+	// 3. The strip itself
+	strip, ok := h.shareLinkStrip(c, link)
+	if !ok {
+		return
+	}
+
+	// 4. Count the view; the condition keeps concurrent views within the limit
+	res := h.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND (max_views IS NULL OR view_count < max_views)", link.ID).
+		Update("view_count", gorm.Expr("view_count + 1"))
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open link"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusGone, gin.H{"error": "This link has reached its view limit"})
+		return
+	}
+
+	expires := time.Now().Add(shareImageTTL).Truncate(time.Second)
+	c.JSON(http.StatusOK, sharedStrip{
+		Title:          strip.Title,
+		Caption:        strip.Caption,
+		CreatedAt:      strip.CreatedAt,
+		ImageURL:       fmt.Sprintf("/api/share/%s/image?exp=%d&sig=%s", link.Slug, expires.Unix(), h.shareImageSignature(link.Slug, expires.Unix())),
+		ImageExpiresAt: expires,
+	})
+}
+
+// GetShareLinkImage streams the image of a shared strip. The URL comes from
+// ResolveShareLink and stops working when it expires or the link does.
+func (h *Handler) GetShareLinkImage(c *gin.Context) {
+	exp, err := strconv.ParseInt(c.Query("exp"), 10, 64)
+	if err != nil || time.Now().Unix() > exp ||
+		!hmac.Equal([]byte(c.Query("sig")), []byte(h.shareImageSignature(c.Param("slug"), exp))) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "This image link has expired"})
+		return
+	}
+
+	link, ok := h.activeShareLink(c)
+	if !ok {
+		return
+	}
+	strip, ok := h.shareLinkStrip(c, link)
+	if !ok {
+		return
+	}
+
+	key := storageKey(strip.FileURL)
+	if key == "" {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory has no image"})
+		return
+	}
+	obj, err := h.openObject(c.Request.Context(), key)
+	if err != nil {
+		log.Printf("GetShareLinkImage Storage Error: %v", err)
+		c.JSON(http.StatusBadGateway, gin.H{"error": "Failed to read from storage"})
+		return
+	}
+	defer obj.Body.Close()
+
+	c.Header("Cache-Control", "private, no-store")
+	c.Header("Content-Disposition", mime.FormatMediaType("inline", map[string]string{
+		"filename": downloadFilename(strip, "png"),
+	}))
+	var size int64 = -1
+	if obj.ContentLength != nil {
+		size = *obj.ContentLength
+	}
+	c.DataFromReader(http.StatusOK, size, "image/png", obj.Body, nil)
+}
+
+// activeShareLink loads the :slug link and checks it hasn't been revoked or
+// expired. It writes the error response itself and returns false on failure.
+func (h *Handler) activeShareLink(c *gin.Context) (models.ShareLink, bool) {
+	var link models.ShareLink
+	if err := h.DB.First(&link, "slug = ?", c.Param("slug")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Link not found"})
+		return link, false
+	}
+	if link.RevokedAt != nil {
+		c.JSON(http.StatusGone, gin.H{"error": "This link has been revoked"})
+		return link, false
+	}
+	if link.ExpiresAt != nil && time.Now().After(*link.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "This link has expired"})
+		return link, false
+	}
+	return link, true
+}
+
+// shareLinkStrip loads the strip a link points to, unless it has expired.
+func (h *Handler) shareLinkStrip(c *gin.Context, link models.ShareLink) (models.Strip, bool) {
+	var strip models.Strip
+	if err := h.DB.First(&strip, "id = ?", link.StripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Memory not found"})
+		return strip, false
+	}
+	if strip.IsGuest && strip.ExpiresAt != nil && time.Now().After(*strip.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "This memory has expired"})
+		return strip, false
+	}
+	return strip, true
+}
+
+// checkShareLinkPassword verifies the X-Share-Password header. Each guess
+// takes one of shareLinkMaxAttempts per shareLinkAttemptWindow before bcrypt
+// runs, so parallel guesses can't get past the limit. A correct password
+// gives the attempts back.
+func (h *Handler) checkShareLinkPassword(c *gin.Context, link models.ShareLink) bool {
+	given := c.GetHeader(ShareLinkPasswordHeader)
+	if given == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Password required", "password_required": true})
+		return false
+	}
+
+	now := time.Now()
+	windowOver := "(password_window_end IS NULL OR password_window_end <= ?)"
+	res := h.DB.Model(&models.ShareLink{}).
+		Where("id = ? AND ("+windowOver+" OR password_attempts < ?)", link.ID, now, shareLinkMaxAttempts).
+		Updates(map[string]interface{}{
+			"password_attempts":   gorm.Expr("CASE WHEN "+windowOver+" THEN 1 ELSE password_attempts + 1 END", now),
+			"password_window_end": gorm.Expr("CASE WHEN "+windowOver+" THEN ? ELSE password_window_end END", now, now.Add(shareLinkAttemptWindow)),
+		})
+	if res.Error != nil {
+		log.Printf("ResolveShareLink DB Error: %v", res.Error)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to open link"})
+		return false
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusTooManyRequests, gin.H{"error": "Too many incorrect passwords, try again later", "password_required": true})
+		return false
+	}
+
+	if bcrypt.CompareHashAndPassword([]byte(link.PasswordHash), []byte(given)) != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Incorrect password", "password_required": true})
+		return false
+	}
+
+	h.DB.Model(&models.ShareLink{}).Where("id = ?", link.ID).Update("password_attempts", 0)
+	return true
+}
+
+// shareImageSignature signs a share link image URL until exp (Unix seconds).
+func (h *Handler) shareImageSignature(slug string, exp int64) string {
+	mac := hmac.New(sha256.New, []byte(h.JWTSecret))
+	fmt.Fprintf(mac, "share-image:%s:%d", slug, exp)
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,175 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"github.com/jackc/pgx/v5/pgconn"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/tokens"
+)
+
+// 10 Crockford characters give 50 bits, far too many to enumerate; collisions
+// are retried. Codes issued before that are 7 characters, so lookups by code
+// are also rate limited on misses.
+const (
+	shortCodeLength     = 10
+	shortCodeAttempts   = 5
+	shortCodeMissLimit  = 30
+	shortCodeMissWindow = 10 * time.Minute
+)
+
+var errNoFreeShortCode = errors.New("could not find a free short code")
+
+// createWithShortCode inserts a new strip together with a fresh short code,
+// retrying with another code on the rare collision.
+func (h *Handler) createWithShortCode(strip *models.Strip) error {
+	for i := 0; i < shortCodeAttempts; i++ {
+		code, err := tokens.ShortCode(shortCodeLength)
+		if err != nil {
+			return err
+		}
+		strip.ShortCode = &code
+		if err := h.DB.Create(strip).Error; !isShortCodeConflict(err) {
+			return err
+		}
+	}
+	strip.ShortCode = nil
+	return errNoFreeShortCode
+}
+
+// assignShortCode gives a strip a fresh short code, retrying on the rare
+// collision with an existing one.
+func (h *Handler) assignShortCode(stripID string) (string, error) {
+	for i := 0; i < shortCodeAttempts; i++ {
+		code, err := tokens.ShortCode(shortCodeLength)
+		if err != nil {
+			return "", err
+		}
+
+		err = h.DB.Unscoped().Model(&models.Strip{}).Where("id = ?", stripID).Update("short_code", code).Error
+		if err == nil {
+			return code, nil
+		}
+		if !isShortCodeConflict(err) {
+			return "", err
+		}
+	}
+	return "", errNoFreeShortCode
+}
+
+// BackfillShortCodes assigns codes to strips saved before short codes existed.
+func (h *Handler) BackfillShortCodes() {
+	var ids []string
+	if err := h.DB.Unscoped().Model(&models.Strip{}).Where("short_code IS NULL").Pluck("id", &ids).Error; err != nil {
+		log.Printf("Backfill Error (Short Codes): %v", err)
+		return
+	}
+
+	for _, id := range ids {
+		if _, err := h.assignShortCode(id); err != nil {
+			log.Printf("Backfill Error (Short Code for %s): %v", id, err)
+		}
+	}
+	if len(ids) > 0 {
+		log.Printf("Assigned short codes to %d strips", len(ids))
+	}
+}
+
+// AdminRegenerateShortCode replaces a strip's short code, e.g. after it
+// leaked. The old code stops resolving immediately.
+func (h *Handler) AdminRegenerateShortCode(c *gin.Context) {
+	stripID := c.Param("id")
+
+	var strip models.Strip
+	if err := h.DB.First(&strip, "id = ?", stripID).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	code, err := h.assignShortCode(strip.ID)
+	if err != nil {
+		log.Printf("AdminRegenerateShortCode Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to regenerate short code"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Short code regenerated", "short_code": code})
+}
+
+// stripByPublicID looks a strip up by UUID or, failing that, by short code.
+func stripByPublicID(db *gorm.DB, id string) *gorm.DB {
+	if _, err := uuid.Parse(id); err == nil {
+		return db.Where("strips.id = ?", id)
+	}
+	return db.Where("strips.short_code = ?", tokens.NormalizeShortCode(id))
+}
+
+func isUniqueViolation(err error) bool {
+	var pgErr *pgconn.PgError
+	return errors.As(err, &pgErr) && pgErr.Code == "23505"
+}
+
+func isShortCodeConflict(err error) bool {
+	var pgErr *pgconn.PgError
+	return isUniqueViolation(err) && errors.As(err, &pgErr) && pgErr.ConstraintName == "idx_strips_short_code"
+}
+
+// missLimiter counts failed lookups per client in fixed windows, so guessing
+// codes is slow while normal visitors never notice it.
+type missLimiter struct {
+	mu      sync.Mutex
+	limit   int
+	window  time.Duration
+	clients map[string]*missWindow
+}
+
+type missWindow struct {
+	count int
+	ends  time.Time
+}
+
+func newMissLimiter(limit int, window time.Duration) *missLimiter {
+	return &missLimiter{limit: limit, window: window, clients: map[string]*missWindow{}}
+}
+
+// blocked reports whether the client has used up its misses for the window.
+func (l *missLimiter) blocked(client string) bool {
+	if l == nil {
+		return false
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	w, ok := l.clients[client]
+	return ok && time.Now().Before(w.ends) && w.count >= l.limit
+}
+
+func (l *missLimiter) miss(client string) {
+	if l == nil {
+		return
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	w, ok := l.clients[client]
+	if !ok || !now.Before(w.ends) {
+		// Drop finished windows now and then so the map can't grow unbounded
+		if len(l.clients) >= 10000 {
+			for k, old := range l.clients {
+				if !now.Before(old.ends) {
+					delete(l.clients, k)
+				}
+			}
+		}
+		w = &missWindow{ends: now.Add(l.window)}
+		l.clients[client] = w
+	}
+	w.count++
+}
@@ -0,0 +1,236 @@
+package handlers
+
+import (
+	"errors"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+)
+
+var stripSorts = map[string]sortColumn{
+	"created_at": {Column: "strips.created_at", IsTime: true},
+	"title":      {Column: "strips.title"},
+}
+
+var userSorts = map[string]sortColumn{
+	"created_at": {Column: "users.created_at", IsTime: true},
+	"username":   {Column: "users.username"},
+}
+
+// Postgres wraps matches in these control characters; they are swapped for
+// <mark> tags only after the snippet has been HTML-escaped.
+const (
+	highlightStart = "\x01"
+	highlightStop  = "\x02"
+	tsQuery        = "websearch_to_tsquery('simple', ?)"
+)
+
+var highlightReplacer = strings.NewReplacer(highlightStart, "<mark>", highlightStop, "</mark>")
+
+// stripSearchResult is a strip with its relevance and highlighted snippets.
+type stripSearchResult struct {
+	models.Strip
+	Rank             float64 `json:"rank"`
+	TitleHighlight   string  `json:"title_highlight"`
+	CaptionHighlight string  `json:"caption_highlight"`
+}
+
+func stripCursorKey(p pageRequest) func(models.Strip) (string, string) {
+	return func(s models.Strip) (string, string) {
+		if p.SortKey == "title" {
+			return s.Title, s.ID
+		}
+		return cursorTime(s.CreatedAt), s.ID
+	}
+}
+
+func userCursorKey(p pageRequest) func(models.User) (string, string) {
+	return func(u models.User) (string, string) {
+		if p.SortKey == "username" {
+			return u.Username, u.ID
+		}
+		return cursorTime(u.CreatedAt), u.ID
+	}
+}
+
+// listStrips serves a paginated, filtered strip listing on top of base. With a
+// q parameter it switches to full-text search, ranked by relevance unless
+// another sort is requested. Admin listings also match the owner's username
+// and email. tags/tags_match filter by the owner's tags; withTags is false for
+// listings of other people's strips, which neither show nor filter by them.
+func (h *Handler) listStrips(c *gin.Context, base *gorm.DB, admin, withTags bool) {
+	term := strings.TrimSpace(c.Query("q"))
+
+	sorts, defaultSort := stripSorts, "created_at"
+	if term != "" {
+		sorts = map[string]sortColumn{
+			"relevance": {Column: "ts_rank(strips.search_vector, " + tsQuery + ")", Args: []interface{}{term}, IsFloat: true},
+		}
+		for k, v := range stripSorts {
+			sorts[k] = v
+		}
+		defaultSort = "relevance"
+	}
+
+	page, err := parsePageRequest(c, sorts, defaultSort, "strips.id")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query, err := applyStripFilters(c, base)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+	if withTags {
+		query, err = applyStripTagFilter(c, query)
+		if err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			return
+		}
+	} else if c.Query("tags") != "" {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags can only filter your own strips"})
+		return
+	}
+	if term != "" {
+		query = applyStripSearch(query, term, admin)
+	}
+
+	// Total is only counted for the first page, where it's cheap enough
+	resp := gin.H{}
+	if page.Cursor == nil {
+		var total int64
+		if err := query.Session(&gorm.Session{}).Count(&total).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+			return
+		}
+		resp["total"] = total
+	}
+
+	query, err = page.apply(query)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	if term == "" {
+		var strips []models.Strip
+		if admin {
+			// Preload User to show who owns it
+			query = query.Preload("User")
+		}
+		if err := query.Find(&strips).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+			return
+		}
+		strips, next := paginate(strips, page, stripCursorKey(page))
+		tagged := make([]*models.Strip, len(strips))
+		for i := range strips {
+			tagged[i] = &strips[i]
+		}
+		if withTags {
+			if err := h.loadStripTags(tagged); err != nil {
+				c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch strips"})
+				return
+			}
+		}
+		resp["strips"] = strips
+		resp["next_cursor"] = next
+		c.JSON(http.StatusOK, resp)
+		return
+	}
+
+	var results []stripSearchResult
+	query = query.Select(
+		"strips.*, ts_rank(strips.search_vector, "+tsQuery+") AS rank, "+
+			"ts_headline('simple', strips.title, "+tsQuery+", ?) AS title_highlight, "+
+			"ts_headline('simple', strips.caption, "+tsQuery+", ?) AS caption_highlight",
+		term,
+		term, "HighlightAll=true, StartSel="+highlightStart+", StopSel="+highlightStop,
+		term, "MaxWords=20, MinWords=5, StartSel="+highlightStart+", StopSel="+highlightStop,
+	)
+	if err := query.Find(&results).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search strips"})
+		return
+	}
+
+	for i := range results {
+		results[i].TitleHighlight = highlightReplacer.Replace(html.EscapeString(results[i].TitleHighlight))
+		results[i].CaptionHighlight = highlightReplacer.Replace(html.EscapeString(results[i].CaptionHighlight))
+	}
+
+	results, next := paginate(results, page, func(r stripSearchResult) (string, string) {
+		if page.SortKey == "relevance" {
+			return cursorFloat(r.Rank), r.ID
+		}
+		return stripCursorKey(page)(r.Strip)
+	})
+	tagged := make([]*models.Strip, len(results))
+	for i := range results {
+		tagged[i] = &results[i].Strip
+	}
+	if withTags {
+		if err := h.loadStripTags(tagged); err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to search strips"})
+			return
+		}
+	}
+	resp["strips"] = results
+	resp["next_cursor"] = next
+	c.JSON(http.StatusOK, resp)
+}
+
+// applyStripSearch restricts a strip query to full-text matches of term. When
+// matchOwner is set, strips whose owner's username or email contains the
+// term also match.
+func applyStripSearch(q *gorm.DB, term string, matchOwner bool) *gorm.DB {
+	cond := "strips.search_vector @@ " + tsQuery
+	if !matchOwner {
+		return q.Where(cond, term)
+	}
+
+	like := "%" + strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(term) + "%"
+	return q.Joins("LEFT JOIN users ON users.id = strips.user_id").
+		Where("("+cond+" OR users.username ILIKE ? OR users.email ILIKE ?)", term, like, like)
+}
+
+// applyStripFilters handles the listing filters shared by user and admin views:
+// from/to (created_at range), type=guest|registered and expired=true|false.
+func applyStripFilters(c *gin.Context, q *gorm.DB) (*gorm.DB, error) {
+	from, to, err := parseDateRange(c)
+	if err != nil {
+		return nil, err
+	}
+	q = applyDateRange(q, "strips.created_at", from, to)
+
+	switch c.Query("type") {
+	case "":
+	case "guest":
+		q = q.Where("strips.is_guest = ?", true)
+	case "registered":
+		q = q.Where("strips.is_guest = ?", false)
+	default:
+		return nil, errors.New("type must be guest or registered")
+	}
+
+	if raw := c.Query("expired"); raw != "" {
+		expired, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, errors.New("expired must be true or false")
+		}
+		if expired {
+			q = q.Where("strips.expires_at IS NOT NULL AND strips.expires_at < ?", time.Now())
+		} else {
+			q = q.Where("(strips.expires_at IS NULL OR strips.expires_at >= ?)", time.Now())
+		}
+	}
+
+	return q, nil
+}
@@ -0,0 +1,334 @@
+package handlers
+
+import (
+	"bytes"
+	"context"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"image"
+	"io"
+	"log"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+)
+
+// decodeImage accepts raw base64 or a data URL ("data:image/png;base64,...").
+func decodeImage(data string) ([]byte, error) {
+	if idx := strings.Index(data, ","); idx != -1 {
+		data = data[idx+1:]
+	}
+	return base64.StdEncoding.DecodeString(data)
+}
+
+// maxImagePixels caps the size of any image the server decodes. A few KB of
+// PNG can claim 30000x30000 pixels, so the header is checked before the
+// pixels are allocated.
+const maxImagePixels = 40_000_000
+
+var errImageTooLarge = errors.New("image dimensions are too large")
+
+// checkImageSize rejects images over maxImagePixels, judged from the header.
+func checkImageSize(cfg image.Config) error {
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > maxImagePixels {
+		return errImageTooLarge
+	}
+	return nil
+}
+
+// decodeImageLimited decodes an image whose header passes checkImageSize.
+func decodeImageLimited(r io.Reader) (image.Image, error) {
+	// DecodeConfig buffers ahead, so replay everything it consumed
+	var header bytes.Buffer
+	cfg, _, err := image.DecodeConfig(io.TeeReader(r, &header))
+	if err != nil {
+		return nil, err
+	}
+	if err := checkImageSize(cfg); err != nil {
+		return nil, err
+	}
+	img, _, err := image.Decode(io.MultiReader(&header, r))
+	return img, err
+}
+
+// stripObjectKey returns a fresh, never-reused key under the strip's prefix:
+// strips/<user-id|guest>/<strip-id>/<object-id>.png. Because every upload gets
+// its own key, a replace can't clobber an object that is still referenced.
+func stripObjectKey(userID, stripID string) string {
+	owner := userID
+	if owner == "" {
+		owner = "guest"
+	}
+	return fmt.Sprintf("strips/%s/%s/%s.png", owner, stripID, uuid.New().String())
+}
+
+// shotObjectKey is where the i-th original shot of a strip is kept:
+// strips/<user-id|guest>/<strip-id>/shots/<i>-<object-id>.<ext>.
+func shotObjectKey(userID, stripID string, i int, ext string) string {
+	owner := userID
+	if owner == "" {
+		owner = "guest"
+	}
+	return fmt.Sprintf("strips/%s/%s/shots/%d-%s.%s", owner, stripID, i, uuid.New().String(), ext)
+}
+
+// derivedPrefix is where renditions of one image object (its OG card,
+// scaled copies) are cached: <image dir>/derived/<object-id>/. Keyed by the
+// image object, so a replaced image never serves an old rendition.
+func derivedPrefix(imageKey string) string {
+	dir, file := path.Split(imageKey)
+	return dir + "derived/" + strings.TrimSuffix(file, path.Ext(file)) + "/"
+}
+
+func derivedObjectKey(imageKey, name string) string {
+	return derivedPrefix(imageKey) + name + ".png"
+}
+
+// storageKey extracts the bucket key from a CDN file URL.
+// URL format: https://<bucket>.<endpoint>/<key>
+func storageKey(fileURL string) string {
+	if fileURL == "" {
+		return ""
+	}
+	u, err := url.Parse(fileURL)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimPrefix(u.Path, "/")
+}
+
+func (h *Handler) cdnURL(key string) string {
+	return fmt.Sprintf("https://%s.sgp1.cdn.digitaloceanspaces.com/%s", h.Bucket, key)
+}
+
+// uploadObject stores a public-read object and returns its CDN URL.
+func (h *Handler) uploadObject(ctx context.Context, key string, data []byte, contentType string) (string, error) {
+	_, err := h.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(h.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+		ACL:         types.ObjectCannedACLPublicRead,
+	})
+	if err != nil {
+		return "", err
+	}
+	return h.cdnURL(key), nil
+}
+
+// uploadPrivateObject stores an object that is only readable through the API.
+func (h *Handler) uploadPrivateObject(ctx context.Context, key string, data []byte, contentType string) error {
+	_, err := h.S3Client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(h.Bucket),
+		Key:         aws.String(key),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String(contentType),
+		ACL:         types.ObjectCannedACLPrivate,
+	})
+	return err
+}
+
+// getObject reads a whole object from the bucket.
+func (h *Handler) getObject(ctx context.Context, key string) ([]byte, error) {
+	out, err := h.openObject(ctx, key)
+	if err != nil {
+		return nil, err
+	}
+	defer out.Body.Close()
+	return io.ReadAll(out.Body)
+}
+
+// copyObject duplicates an object within the bucket under a new key.
+func (h *Handler) copyObject(ctx context.Context, srcKey, dstKey string, acl types.ObjectCannedACL) error {
+	_, err := h.S3Client.CopyObject(ctx, &s3.CopyObjectInput{
+		Bucket:     aws.String(h.Bucket),
+		CopySource: aws.String(h.Bucket + "/" + srcKey),
+		Key:        aws.String(dstKey),
+		ACL:        acl,
+	})
+	return err
+}
+
+// openObject streams an object from the bucket. The caller closes the body.
+func (h *Handler) openObject(ctx context.Context, key string) (*s3.GetObjectOutput, error) {
+	return h.S3Client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(key),
+	})
+}
+
+// objectExists reports whether key is in the bucket.
+func (h *Handler) objectExists(ctx context.Context, key string) (bool, error) {
+	_, err := h.S3Client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(key),
+	})
+	var notFound *types.NotFound
+	if errors.As(err, &notFound) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (h *Handler) deleteObject(ctx context.Context, key string) {
+	_, err := h.S3Client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(h.Bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		log.Printf("Failed to delete S3 object %s: %v", key, err)
+	} else {
+		log.Printf("Deleted S3 object: %s", key)
+	}
+}
+
+// deleteDerivedObjects removes every cached rendition of an image object.
+func (h *Handler) deleteDerivedObjects(ctx context.Context, imageKey string) {
+	if imageKey == "" {
+		return
+	}
+	pages := s3.NewListObjectsV2Paginator(h.S3Client, &s3.ListObjectsV2Input{
+		Bucket: aws.String(h.Bucket),
+		Prefix: aws.String(derivedPrefix(imageKey)),
+	})
+	for pages.HasMorePages() {
+		out, err := pages.NextPage(ctx)
+		if err != nil {
+			log.Printf("Failed to list renditions of %s: %v", imageKey, err)
+			return
+		}
+		for _, obj := range out.Contents {
+			h.deleteObject(ctx, aws.ToString(obj.Key))
+		}
+	}
+}
+
+// deleteStripObject removes a strip's image, earlier versions, their cached
+// renditions and original shots from storage. Call it before deleting the
+// strip row, which takes the version rows with it. Failures are logged rather
+// than returned so callers can still keep the DB in line with user intent.
+func (h *Handler) deleteStripObject(ctx context.Context, strip models.Strip) {
+	if key := storageKey(strip.FileURL); key != "" {
+		h.deleteObject(ctx, key)
+		h.deleteDerivedObjects(ctx, key)
+	}
+	for _, key := range strip.ShotKeys {
+		h.deleteObject(ctx, key)
+	}
+
+	var versionKeys []string
+	if err := h.DB.Model(&models.StripVersion{}).Where("strip_id = ?", strip.ID).Pluck("storage_key", &versionKeys).Error; err != nil {
+		log.Printf("Failed to list versions of strip %s: %v", strip.ID, err)
+	}
+	for _, key := range versionKeys {
+		h.deleteObject(ctx, key)
+		h.deleteDerivedObjects(ctx, key)
+	}
+}
+
+var errStripModified = errors.New("strip was modified concurrently")
+
+// updateStripImage applies updates (which must include file_url) to the
+// strip. The update is guarded on the old URL so concurrent replaces can't
+// interleave.
+func updateStripImage(tx *gorm.DB, strip models.Strip, updates map[string]interface{}) error {
+	res := tx.Model(&models.Strip{}).
+		Where("id = ? AND file_url = ?", strip.ID, strip.FileURL).
+		Updates(updates)
+	if res.Error != nil {
+		return res.Error
+	}
+	if res.RowsAffected == 0 {
+		return errStripModified
+	}
+	return nil
+}
+
+// swapStripImage is updateStripImage that also records the current image as
+// a version.
+func swapStripImage(tx *gorm.DB, strip models.Strip, updates map[string]interface{}) error {
+	if err := updateStripImage(tx, strip, updates); err != nil {
+		return err
+	}
+
+	oldKey := storageKey(strip.FileURL)
+	if oldKey == "" {
+		return nil
+	}
+	return tx.Create(&models.StripVersion{
+		ID:             uuid.New().String(),
+		StripID:        strip.ID,
+		StorageKey:     oldKey,
+		RenderSettings: strip.RenderSettings,
+		CreatedAt:      time.Now(),
+	}).Error
+}
+
+// replaceStripImage uploads the new image under a fresh key and points the
+// strip at it. The previous image is kept as a StripVersion, unless amend is
+// set: then it is deleted, as when the save flow stamps the QR onto a strip
+// it just uploaded. If the DB update fails the new object is removed and the
+// strip keeps serving the old one. extra holds further columns to update
+// together with file_url.
+func (h *Handler) replaceStripImage(c *gin.Context, strip models.Strip, imgBytes []byte, extra map[string]interface{}, amend bool) {
+	ownerID := ""
+	if strip.UserID != nil {
+		ownerID = *strip.UserID
+	}
+	newKey := stripObjectKey(ownerID, strip.ID)
+
+	// 1. Upload alongside the current object
+	newURL, err := h.uploadObject(c.Request.Context(), newKey, imgBytes, "image/png")
+	if err != nil {
+		log.Printf("ReplaceStripImage Upload Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to upload to storage"})
+		return
+	}
+
+	// 2. Swap the reference and keep the old image as a version
+	updates := map[string]interface{}{"file_url": newURL}
+	for k, v := range extra {
+		updates[k] = v
+	}
+	err = h.DB.Transaction(func(tx *gorm.DB) error {
+		if amend {
+			return updateStripImage(tx, strip, updates)
+		}
+		return swapStripImage(tx, strip, updates)
+	})
+	if err != nil {
+		h.deleteObject(context.Background(), newKey)
+		if errors.Is(err, errStripModified) {
+			c.JSON(http.StatusConflict, gin.H{"error": "Strip was modified concurrently, please retry"})
+		} else {
+			log.Printf("ReplaceStripImage DB Error: %v", err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to save to database"})
+		}
+		return
+	}
+
+	// 3. Renditions of the old image are no longer served
+	oldKey := storageKey(strip.FileURL)
+	h.deleteDerivedObjects(context.Background(), oldKey)
+	if amend && oldKey != "" {
+		h.deleteObject(context.Background(), oldKey)
+	}
+
+	log.Printf("ReplaceStripImage SUCCESS: id=%s, file_url=%s", strip.ID, newURL)
+	c.JSON(http.StatusOK, gin.H{
+		"message":  "Strip image replaced",
+		"file_url": newURL,
+		"id":       strip.ID,
+	})
+}
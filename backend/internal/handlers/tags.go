@@ -0,0 +1,340 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"web-photobooth/backend/internal/models"
+)
+
+const (
+	maxTagLength      = 40
+	maxTagsPerStrip   = 20
+	maxTagFilterSlugs = 10
+)
+
+var tagSlugUnsafeChars = regexp.MustCompile(`[^\p{L}\p{M}\p{N}]+`)
+
+// tagSlug normalizes a tag name: lowercase, with runs of anything other than
+// letters and digits, in any script, collapsed to a single dash
+// ("Beach Day!" -> "beach-day", "Café" -> "café").
+func tagSlug(name string) string {
+	slug := tagSlugUnsafeChars.ReplaceAllString(strings.ToLower(strings.TrimSpace(name)), "-")
+	slug = strings.Trim(slug, "-")
+	if runes := []rune(slug); len(runes) > maxTagLength {
+		slug = strings.TrimRight(string(runes[:maxTagLength]), "-")
+	}
+	return slug
+}
+
+// tagUsage is a tag with the number of strips carrying it.
+type tagUsage struct {
+	Name  string `json:"name"`
+	Slug  string `json:"slug"`
+	Count int64  `json:"count"`
+}
+
+// AddStripTags attaches tags to one of the caller's strips, creating any tags
+// the user hasn't used before.
+func (h *Handler) AddStripTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+
+	var req struct {
+		Tags []string `json:"tags"`
+	}
+
+	if err := c.ShouldBindJSON(&req); err != nil || len(req.Tags) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "tags required"})
+		return
+	}
+
+	// 1. Normalize, dropping duplicates and names with nothing usable in them
+	slugs, names := normalizeTagNames(req.Tags)
+	if len(slugs) == 0 {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Tags must contain letters or digits"})
+		return
+	}
+
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", stripID, userID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	// 2. Find or create the tags and link them
+	err := attachTags(h.DB, userID, strip.ID, slugs, names)
+	if errors.Is(err, errTooManyTags) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "A strip can have at most 20 tags"})
+		return
+	}
+	if err != nil {
+		log.Printf("AddStripTags DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to tag strip"})
+		return
+	}
+
+	strips := []*models.Strip{&strip}
+	if err := h.loadStripTags(strips); err != nil {
+		log.Printf("AddStripTags DB Error: %v", err)
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Tags added", "tags": strip.Tags})
+}
+
+var errTooManyTags = errors.New("too many tags")
+
+// normalizeTagNames turns raw names into unique slugs, in order, with the
+// display name to use for each. Names with nothing usable in them are dropped.
+func normalizeTagNames(raw []string) ([]string, map[string]string) {
+	names := map[string]string{}
+	var slugs []string
+	for _, name := range raw {
+		slug := tagSlug(name)
+		if slug == "" {
+			continue
+		}
+		if _, ok := names[slug]; !ok {
+			names[slug] = strings.TrimSpace(name)
+			slugs = append(slugs, slug)
+		}
+	}
+	return slugs, names
+}
+
+// attachTags links the user's tags to a strip, creating any the user hasn't
+// used before. It fails with errTooManyTags if the strip would end up with
+// more than maxTagsPerStrip.
+func attachTags(db *gorm.DB, userID, stripID string, slugs []string, names map[string]string) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		// 1. Find or create the user's tags
+		newTags := make([]models.Tag, 0, len(slugs))
+		for _, slug := range slugs {
+			name := names[slug]
+			if len(name) > maxTagLength {
+				name = slug
+			}
+			newTags = append(newTags, models.Tag{ID: uuid.New().String(), UserID: userID, Name: name, Slug: slug, CreatedAt: time.Now()})
+		}
+		if err := tx.Clauses(clause.OnConflict{
+			Columns:   []clause.Column{{Name: "user_id"}, {Name: "slug"}},
+			DoNothing: true,
+		}).Create(&newTags).Error; err != nil {
+			return err
+		}
+
+		var tags []models.Tag
+		if err := tx.Where("user_id = ? AND slug IN ?", userID, slugs).Find(&tags).Error; err != nil {
+			return err
+		}
+
+		// 2. Link them to the strip
+		links := make([]models.StripTag, 0, len(tags))
+		for _, tag := range tags {
+			links = append(links, models.StripTag{StripID: stripID, TagID: tag.ID, CreatedAt: time.Now()})
+		}
+		if err := tx.Clauses(clause.OnConflict{DoNothing: true}).Create(&links).Error; err != nil {
+			return err
+		}
+
+		var count int64
+		if err := tx.Model(&models.StripTag{}).Where("strip_id = ?", stripID).Count(&count).Error; err != nil {
+			return err
+		}
+		if count > maxTagsPerStrip {
+			return errTooManyTags
+		}
+		return nil
+	})
+}
+
+// RemoveStripTag detaches a tag from one of the caller's strips. A tag left
+// on no strips is deleted so it stops showing up in autocomplete.
+func (h *Handler) RemoveStripTag(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+	slug := tagSlug(c.Param("slug"))
+
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", stripID, userID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	var tag models.Tag
+	if err := h.DB.Where("user_id = ? AND slug = ?", userID, slug).First(&tag).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Tag not found"})
+		return
+	}
+
+	var removed int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Where("strip_id = ? AND tag_id = ?", strip.ID, tag.ID).Delete(&models.StripTag{})
+		if res.Error != nil {
+			return res.Error
+		}
+		removed = res.RowsAffected
+
+		return tx.Where("id = ? AND NOT EXISTS (SELECT 1 FROM strip_tags WHERE strip_tags.tag_id = tags.id)", tag.ID).
+			Delete(&models.Tag{}).Error
+	})
+	if err != nil {
+		log.Printf("RemoveStripTag DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to remove tag"})
+		return
+	}
+	if removed == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip does not have this tag"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Tag removed"})
+}
+
+// ListTags autocompletes the caller's tags. q matches slugs by prefix; the
+// most used tags come first.
+func (h *Handler) ListTags(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	limit, err := queryLimit(c, 20)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Model(&models.Tag{}).
+		Select("tags.name, tags.slug, COUNT(strip_tags.strip_id) AS count").
+		Joins("LEFT JOIN strip_tags ON strip_tags.tag_id = tags.id").
+		Where("tags.user_id = ?", userID)
+	if prefix := tagSlug(c.Query("q")); prefix != "" {
+		query = query.Where("tags.slug LIKE ?", prefix+"%")
+	}
+
+	tags := []tagUsage{}
+	if err := query.Group("tags.id, tags.name, tags.slug").
+		Order("count DESC, tags.slug ASC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		log.Printf("ListTags DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// AdminGetPopularTags lists the most used tags across all users, grouped by
+// slug.
+func (h *Handler) AdminGetPopularTags(c *gin.Context) {
+	limit, err := queryLimit(c, 50)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	tags := []tagUsage{}
+	if err := h.DB.Model(&models.StripTag{}).
+		Select("tags.slug, MIN(tags.name) AS name, COUNT(*) AS count").
+		Joins("JOIN tags ON tags.id = strip_tags.tag_id").
+		Group("tags.slug").
+		Order("count DESC, tags.slug ASC").
+		Limit(limit).
+		Scan(&tags).Error; err != nil {
+		log.Printf("AdminGetPopularTags DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch tags"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"tags": tags})
+}
+
+// applyStripTagFilter handles tags=a,b with tags_match=all (default, strips
+// carrying every tag) or any (strips carrying at least one).
+func applyStripTagFilter(c *gin.Context, q *gorm.DB) (*gorm.DB, error) {
+	raw := c.Query("tags")
+	if raw == "" {
+		return q, nil
+	}
+
+	var slugs []string
+	seen := map[string]bool{}
+	for _, name := range strings.Split(raw, ",") {
+		if slug := tagSlug(name); slug != "" && !seen[slug] {
+			seen[slug] = true
+			slugs = append(slugs, slug)
+		}
+	}
+	if len(slugs) == 0 {
+		return q, nil
+	}
+	if len(slugs) > maxTagFilterSlugs {
+		return nil, errors.New("at most 10 tags can be filtered on")
+	}
+
+	// Tags are per user, so the tag must belong to the strip's owner
+	sub := "SELECT strip_tags.strip_id FROM strip_tags JOIN tags ON tags.id = strip_tags.tag_id " +
+		"WHERE tags.slug IN ? AND tags.user_id = strips.user_id"
+
+	switch c.DefaultQuery("tags_match", "all") {
+	case "any":
+		return q.Where("strips.id IN ("+sub+")", slugs), nil
+	case "all":
+		return q.Where("strips.id IN ("+sub+" GROUP BY strip_tags.strip_id HAVING COUNT(*) = ?)", slugs, len(slugs)), nil
+	default:
+		return nil, errors.New("tags_match must be all or any")
+	}
+}
+
+// loadStripTags fills in Tags on each strip.
+func (h *Handler) loadStripTags(strips []*models.Strip) error {
+	if len(strips) == 0 {
+		return nil
+	}
+
+	byID := make(map[string]*models.Strip, len(strips))
+	ids := make([]string, 0, len(strips))
+	for _, s := range strips {
+		byID[s.ID] = s
+		ids = append(ids, s.ID)
+	}
+
+	var rows []struct {
+		StripID string
+		models.Tag
+	}
+	if err := h.DB.Model(&models.StripTag{}).
+		Select("strip_tags.strip_id, tags.*").
+		Joins("JOIN tags ON tags.id = strip_tags.tag_id").
+		Where("strip_tags.strip_id IN ?", ids).
+		Order("tags.slug ASC").
+		Scan(&rows).Error; err != nil {
+		return err
+	}
+
+	for _, row := range rows {
+		if s, ok := byID[row.StripID]; ok {
+			s.Tags = append(s.Tags, row.Tag)
+		}
+	}
+	return nil
+}
+
+func queryLimit(c *gin.Context, def int) (int, error) {
+	raw := c.Query("limit")
+	if raw == "" {
+		return def, nil
+	}
+	n, err := strconv.Atoi(raw)
+	if err != nil || n < 1 {
+		return 0, errors.New("limit must be a positive integer")
+	}
+	return min(n, maxPageLimit), nil
+}
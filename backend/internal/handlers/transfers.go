@@ -0,0 +1,447 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"web-photobooth/backend/internal/models"
+)
+
+// Offers the recipient doesn't act on lapse after a week.
+const transferOfferTTL = 7 * 24 * time.Hour
+
+var errTransferClosed = errors.New("transfer offer is no longer open")
+
+// transferView is an offer with both parties' usernames.
+type transferView struct {
+	models.StripTransfer
+	FromUsername string `json:"from_username"`
+	ToUsername   string `json:"to_username"`
+}
+
+// CreateStripTransfer offers a strip the caller owns to another user, named
+// by username or email. A strip can have one open offer at a time.
+func (h *Handler) CreateStripTransfer(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var req struct {
+		Recipient string `json:"recipient" binding:"required"` // Username or email
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipient is required"})
+		return
+	}
+
+	strip, ok := h.ownedStrip(c)
+	if !ok {
+		return
+	}
+
+	recipient, ok := h.transferRecipient(c, req.Recipient)
+	if !ok {
+		return
+	}
+	if recipient.ID == userID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "You already own this strip"})
+		return
+	}
+
+	var open int64
+	if err := h.DB.Model(&models.StripTransfer{}).
+		Where("strip_id = ? AND status = ? AND expires_at > ?", strip.ID, models.TransferPending, time.Now()).
+		Count(&open).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+	if open > 0 {
+		c.JSON(http.StatusConflict, gin.H{"error": "This strip already has an open transfer offer"})
+		return
+	}
+
+	now := time.Now()
+	offer := models.StripTransfer{
+		ID:         uuid.New().String(),
+		StripID:    strip.ID,
+		FromUserID: userID,
+		ToUserID:   recipient.ID,
+		Status:     models.TransferPending,
+		ExpiresAt:  now.Add(transferOfferTTL),
+		CreatedAt:  now,
+	}
+	if err := h.DB.Create(&offer).Error; err != nil {
+		log.Printf("CreateStripTransfer DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create transfer"})
+		return
+	}
+
+	offer.Strip = strip
+	c.JSON(http.StatusCreated, transferView{StripTransfer: offer, ToUsername: recipient.Username})
+}
+
+// ListTransfers returns the caller's open offers, sent and received.
+func (h *Handler) ListTransfers(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	var offers []models.StripTransfer
+	if err := h.DB.Preload("Strip").Preload("FromUser").Preload("ToUser").
+		Where("(from_user_id = ? OR to_user_id = ?) AND status = ? AND expires_at > ?", userID, userID, models.TransferPending, time.Now()).
+		Where("strip_id IN (SELECT id FROM strips WHERE deleted_at IS NULL)").
+		Order("created_at DESC").
+		Find(&offers).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch transfers"})
+		return
+	}
+
+	incoming, outgoing := []transferView{}, []transferView{}
+	for _, o := range offers {
+		view := transferView{StripTransfer: o, FromUsername: o.FromUser.Username, ToUsername: o.ToUser.Username}
+		if o.ToUserID == userID {
+			incoming = append(incoming, view)
+		} else {
+			outgoing = append(outgoing, view)
+		}
+	}
+	c.JSON(http.StatusOK, gin.H{"incoming": incoming, "outgoing": outgoing})
+}
+
+// AcceptStripTransfer moves the strip to the caller, who must be the offer's
+// recipient.
+func (h *Handler) AcceptStripTransfer(c *gin.Context) {
+	userID := c.GetString("user_id")
+
+	// 1. The offer must be open and addressed to the caller
+	var offer models.StripTransfer
+	if err := h.DB.Where("id = ? AND to_user_id = ?", c.Param("id"), userID).First(&offer).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return
+	}
+	if offer.Status != models.TransferPending {
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer open"})
+		return
+	}
+	if time.Now().After(offer.ExpiresAt) {
+		c.JSON(http.StatusGone, gin.H{"error": "This transfer has expired"})
+		return
+	}
+
+	// 2. The sender must still own the strip
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", offer.StripID, offer.FromUserID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusConflict, gin.H{"error": "The strip is no longer available"})
+		return
+	}
+
+	// 3. Move it, closing the offer in the same transaction
+	err := h.transferStrip(c.Request.Context(), strip, userID, userID, models.AuditStripTransfer, func(tx *gorm.DB) error {
+		res := tx.Model(&models.StripTransfer{}).
+			Where("id = ? AND status = ?", offer.ID, models.TransferPending).
+			Updates(map[string]interface{}{"status": models.TransferAccepted, "responded_at": time.Now()})
+		if res.Error != nil {
+			return res.Error
+		}
+		if res.RowsAffected == 0 {
+			return errTransferClosed
+		}
+		return nil
+	})
+	if !h.transferResponse(c, err, "AcceptStripTransfer") {
+		return
+	}
+
+	log.Printf("AcceptStripTransfer: strip %s moved from %s to %s", strip.ID, offer.FromUserID, userID)
+	h.DB.First(&strip, "id = ?", strip.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Transfer accepted", "strip": strip})
+}
+
+func (h *Handler) DeclineStripTransfer(c *gin.Context) {
+	h.closeTransfer(c, "to_user_id", models.TransferDeclined, "Transfer declined")
+}
+
+func (h *Handler) CancelStripTransfer(c *gin.Context) {
+	h.closeTransfer(c, "from_user_id", models.TransferCancelled, "Transfer cancelled")
+}
+
+// closeTransfer ends an open offer on behalf of one of its parties.
+func (h *Handler) closeTransfer(c *gin.Context, partyColumn, status, message string) {
+	res := h.DB.Model(&models.StripTransfer{}).
+		Where("id = ? AND "+partyColumn+" = ? AND status = ?", c.Param("id"), c.GetString("user_id"), models.TransferPending).
+		Updates(map[string]interface{}{"status": status, "responded_at": time.Now()})
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update transfer"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Transfer not found"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": message})
+}
+
+// AdminTransferStrip moves a strip to another user without an offer. Guest
+// strips become regular strips of the recipient, as if claimed.
+func (h *Handler) AdminTransferStrip(c *gin.Context) {
+	adminID := c.GetString("user_id")
+
+	var req struct {
+		Recipient string `json:"recipient" binding:"required"` // Username or email
+	}
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "recipient is required"})
+		return
+	}
+
+	var strip models.Strip
+	if err := h.DB.First(&strip, "id = ?", c.Param("id")).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return
+	}
+
+	recipient, ok := h.transferRecipient(c, req.Recipient)
+	if !ok {
+		return
+	}
+	if strip.UserID != nil && *strip.UserID == recipient.ID {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "The recipient already owns this strip"})
+		return
+	}
+
+	err := h.transferStrip(c.Request.Context(), strip, recipient.ID, adminID, models.AuditStripForceTransfer, nil)
+	if !h.transferResponse(c, err, "AdminTransferStrip") {
+		return
+	}
+
+	log.Printf("AdminTransferStrip: admin %s moved strip %s to %s", adminID, strip.ID, recipient.ID)
+	h.DB.First(&strip, "id = ?", strip.ID)
+	c.JSON(http.StatusOK, gin.H{"message": "Strip transferred", "strip": strip})
+}
+
+// AdminGetAuditLog lists the newest audit entries, optionally for one strip
+// (?strip_id=) or user (?user_id=, as actor or either party).
+func (h *Handler) AdminGetAuditLog(c *gin.Context) {
+	limit, err := queryLimit(c, 100)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		return
+	}
+
+	query := h.DB.Model(&models.AuditLog{})
+	if stripID := c.Query("strip_id"); stripID != "" {
+		query = query.Where("strip_id = ?", stripID)
+	}
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("actor_id = ? OR from_user_id = ? OR to_user_id = ?", userID, userID, userID)
+	}
+
+	entries := []models.AuditLog{}
+	if err := query.Order("created_at DESC").Limit(limit).Find(&entries).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch audit log"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"entries": entries})
+}
+
+func (h *Handler) transferRecipient(c *gin.Context, identifier string) (models.User, bool) {
+	identifier = strings.TrimSpace(identifier)
+
+	var user models.User
+	if err := h.DB.Where("email = ? OR username = ?", identifier, identifier).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Recipient not found"})
+		return user, false
+	}
+	return user, true
+}
+
+// transferResponse writes the error response for a failed transferStrip and
+// reports whether the transfer went through.
+func (h *Handler) transferResponse(c *gin.Context, err error, op string) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, errStripModified):
+		c.JSON(http.StatusConflict, gin.H{"error": "Strip was modified concurrently, please retry"})
+	case errors.Is(err, errTransferClosed):
+		c.JSON(http.StatusConflict, gin.H{"error": "This transfer is no longer open"})
+	default:
+		log.Printf("%s Error: %v", op, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to transfer strip"})
+	}
+	return false
+}
+
+// objectMove is one storage object being re-keyed under a new owner.
+type objectMove struct {
+	from, to string
+	acl      types.ObjectCannedACL
+}
+
+// transferStrip hands strip to toUserID. Its image, versions and shots are
+// copied under strips/<new-owner>/ first; the old objects are deleted only
+// once the DB points at the copies. Anything tied to the previous owner
+// (their albums, tags and share links) is detached, and an audit entry is
+// written. inTx, if set, runs inside the same transaction.
+func (h *Handler) transferStrip(ctx context.Context, strip models.Strip, toUserID, actorID, action string, inTx func(tx *gorm.DB) error) error {
+	// 1. Copy every object under the new owner's prefix
+	var versions []models.StripVersion
+	if err := h.DB.Where("strip_id = ?", strip.ID).Find(&versions).Error; err != nil {
+		return err
+	}
+
+	var moves []objectMove
+	imageKey := storageKey(strip.FileURL)
+	newImageKey := ""
+	if imageKey != "" {
+		newImageKey = stripObjectKey(toUserID, strip.ID)
+		moves = append(moves, objectMove{imageKey, newImageKey, types.ObjectCannedACLPublicRead})
+	}
+	newShotKeys := make(models.StringList, 0, len(strip.ShotKeys))
+	for i, key := range strip.ShotKeys {
+		newKey := shotObjectKey(toUserID, strip.ID, i, strings.TrimPrefix(path.Ext(key), "."))
+		newShotKeys = append(newShotKeys, newKey)
+		moves = append(moves, objectMove{key, newKey, types.ObjectCannedACLPrivate})
+	}
+	newVersionKeys := make([]string, len(versions))
+	for i, v := range versions {
+		newVersionKeys[i] = stripObjectKey(toUserID, strip.ID)
+		moves = append(moves, objectMove{v.StorageKey, newVersionKeys[i], types.ObjectCannedACLPublicRead})
+	}
+
+	for i, m := range moves {
+		if err := h.copyObject(ctx, m.from, m.to, m.acl); err != nil {
+			for _, done := range moves[:i] {
+				h.deleteObject(context.Background(), done.to)
+			}
+			return err
+		}
+	}
+
+	// 2. Point the strip at the copies and detach the old owner
+	var prunedVersionKeys []string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		var current models.Strip
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&current, "id = ?", strip.ID).Error; err != nil {
+			return err
+		}
+		if current.FileURL != strip.FileURL || !sameOwner(current.UserID, strip.UserID) {
+			return errStripModified
+		}
+
+		if inTx != nil {
+			if err := inTx(tx); err != nil {
+				return err
+			}
+		}
+
+		updates := map[string]interface{}{"user_id": toUserID}
+		if newImageKey != "" {
+			updates["file_url"] = h.cdnURL(newImageKey)
+		}
+		if len(newShotKeys) > 0 {
+			updates["shot_keys"] = newShotKeys
+		}
+		if strip.IsGuest {
+			updates["is_guest"] = false
+			updates["expires_at"] = nil
+			updates["claim_token_hash"] = ""
+			updates["manage_token_hash"] = ""
+		}
+		if err := tx.Model(&models.Strip{}).Where("id = ?", strip.ID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		// A version pruned since step 1 leaves its copy behind; clean that up after
+		for i, v := range versions {
+			res := tx.Model(&models.StripVersion{}).
+				Where("id = ? AND storage_key = ?", v.ID, v.StorageKey).
+				Update("storage_key", newVersionKeys[i])
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				prunedVersionKeys = append(prunedVersionKeys, newVersionKeys[i])
+			}
+		}
+
+		if strip.UserID != nil {
+			if err := detachFromOwner(tx, strip.ID, *strip.UserID); err != nil {
+				return err
+			}
+		}
+
+		if err := tx.Model(&models.StripTransfer{}).
+			Where("strip_id = ? AND status = ?", strip.ID, models.TransferPending).
+			Updates(map[string]interface{}{"status": models.TransferCancelled, "responded_at": time.Now()}).Error; err != nil {
+			return err
+		}
+
+		stripID, to := strip.ID, toUserID
+		return tx.Create(&models.AuditLog{
+			ID:         uuid.New().String(),
+			ActorID:    actorID,
+			Action:     action,
+			StripID:    &stripID,
+			FromUserID: strip.UserID,
+			ToUserID:   &to,
+			CreatedAt:  time.Now(),
+		}).Error
+	})
+	if err != nil {
+		for _, m := range moves {
+			h.deleteObject(context.Background(), m.to)
+		}
+		return err
+	}
+
+	// 3. Only the copies are referenced now
+	for _, m := range moves {
+		h.deleteObject(context.Background(), m.from)
+	}
+	h.deleteDerivedObjects(context.Background(), imageKey)
+	for _, key := range prunedVersionKeys {
+		h.deleteObject(context.Background(), key)
+	}
+	return nil
+}
+
+// detachFromOwner removes a strip from its previous owner's albums and tags
+// and revokes the share links they created.
+func detachFromOwner(tx *gorm.DB, stripID, ownerID string) error {
+	if err := tx.Where("strip_id = ? AND album_id IN (SELECT id FROM albums WHERE user_id = ?)", stripID, ownerID).
+		Delete(&models.AlbumStrip{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Model(&models.Album{}).
+		Where("user_id = ? AND cover_strip_id = ?", ownerID, stripID).
+		Update("cover_strip_id", nil).Error; err != nil {
+		return err
+	}
+
+	if err := tx.Where("strip_id = ?", stripID).Delete(&models.StripTag{}).Error; err != nil {
+		return err
+	}
+	if err := tx.Where("user_id = ? AND NOT EXISTS (SELECT 1 FROM strip_tags WHERE strip_tags.tag_id = tags.id)", ownerID).
+		Delete(&models.Tag{}).Error; err != nil {
+		return err
+	}
+
+	return tx.Model(&models.ShareLink{}).
+		Where("strip_id = ? AND user_id = ? AND revoked_at IS NULL", stripID, ownerID).
+		Update("revoked_at", time.Now()).Error
+}
+
+func sameOwner(a, b *string) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return *a == *b
+}
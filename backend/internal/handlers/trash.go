@@ -0,0 +1,161 @@
+package handlers
+
+import (
+	"context"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"web-photobooth/backend/internal/models"
+)
+
+// GetTrashedStrips lists the caller's deleted strips that can still be
+// restored. It takes the same paging and filters as GetMyStrips.
+func (h *Handler) GetTrashedStrips(c *gin.Context) {
+	userID := c.GetString("user_id")
+	h.listStrips(c, h.DB.Unscoped().Model(&models.Strip{}).
+		Where("strips.user_id = ? AND strips.deleted_at IS NOT NULL", userID), false, true)
+}
+
+func (h *Handler) RestoreStrip(c *gin.Context) {
+	userID := c.GetString("user_id")
+	stripID := c.Param("id")
+
+	res := h.DB.Unscoped().Model(&models.Strip{}).
+		Where("id = ? AND user_id = ? AND deleted_at IS NOT NULL", stripID, userID).
+		Update("deleted_at", nil)
+	if res.Error != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore strip"})
+		return
+	}
+	if res.RowsAffected == 0 {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found in trash"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strip restored"})
+}
+
+func (h *Handler) AdminGetTrashedStrips(c *gin.Context) {
+	query := h.DB.Unscoped().Model(&models.Strip{}).Where("strips.deleted_at IS NOT NULL")
+	if userID := c.Query("user_id"); userID != "" {
+		query = query.Where("strips.user_id = ?", userID)
+	}
+	h.listStrips(c, query, true, true)
+}
+
+// userActive reports whether a user exists and isn't in the trash. A trashed
+// user's tokens stay valid until they expire, so AuthMiddleware asks on every
+// request.
+func (h *Handler) userActive(userID string) (bool, error) {
+	var count int64
+	if err := h.DB.Model(&models.User{}).Where("id = ?", userID).Count(&count).Error; err != nil {
+		log.Printf("Auth DB Error: %v", err)
+		return false, err
+	}
+	return count > 0, nil
+}
+
+func (h *Handler) AdminGetTrashedUsers(c *gin.Context) {
+	h.listUsers(c, h.DB.Unscoped().Model(&models.User{}).Where("users.deleted_at IS NOT NULL"))
+}
+
+// AdminRestoreStrip restores any strip. A strip whose owner is in the trash
+// can only come back with the owner.
+func (h *Handler) AdminRestoreStrip(c *gin.Context) {
+	stripID := c.Param("id")
+
+	var strip models.Strip
+	if err := h.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", stripID).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found in trash"})
+		return
+	}
+
+	if strip.UserID != nil {
+		var owners int64
+		if err := h.DB.Model(&models.User{}).Where("id = ?", *strip.UserID).Count(&owners).Error; err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore strip"})
+			return
+		}
+		if owners == 0 {
+			c.JSON(http.StatusConflict, gin.H{"error": "The owner of this memory is deleted; restore the user instead"})
+			return
+		}
+	}
+
+	if err := h.DB.Unscoped().Model(&strip).Update("deleted_at", nil).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore strip"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Strip restored"})
+}
+
+// AdminRestoreUser restores a user together with the strips that were
+// trashed when the user was deleted. Strips the user had already deleted
+// themselves stay in the trash.
+func (h *Handler) AdminRestoreUser(c *gin.Context) {
+	userID := c.Param("id")
+
+	var user models.User
+	if err := h.DB.Unscoped().Where("id = ? AND deleted_at IS NOT NULL", userID).First(&user).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "User not found in trash"})
+		return
+	}
+
+	var restored int64
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		res := tx.Unscoped().Model(&models.Strip{}).
+			Where("user_id = ? AND deleted_at = ?", user.ID, user.DeletedAt.Time).
+			Update("deleted_at", nil)
+		if res.Error != nil {
+			return res.Error
+		}
+		restored = res.RowsAffected
+		return tx.Unscoped().Model(&user).Update("deleted_at", nil).Error
+	})
+	if err != nil {
+		log.Printf("AdminRestoreUser DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore user"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "User restored", "restored_strips": restored})
+}
+
+// PurgeTrash permanently removes strips and users that have been in the
+// trash longer than the retention window, storage objects first.
+func (h *Handler) PurgeTrash() {
+	cutoff := time.Now().Add(-h.TrashRetention)
+	ctx := context.Background()
+
+	// 1. Strips, including those of purged users
+	var strips []models.Strip
+	if err := h.DB.Unscoped().
+		Where("deleted_at < ? OR user_id IN (SELECT id FROM users WHERE deleted_at < ?)", cutoff, cutoff).
+		Find(&strips).Error; err != nil {
+		log.Printf("Purge Error (Find Strips): %v", err)
+		return
+	}
+
+	for _, strip := range strips {
+		h.deleteStripObject(ctx, strip)
+		if err := h.DB.Unscoped().Delete(&strip).Error; err != nil {
+			log.Printf("Purge Error (DB Delete Strip %s): %v", strip.ID, err)
+		}
+	}
+
+	// 2. Users; their albums and tags go with them
+	res := h.DB.Unscoped().
+		Where("deleted_at < ? AND NOT EXISTS (SELECT 1 FROM strips WHERE strips.user_id = users.id)", cutoff).
+		Delete(&models.User{})
+	if res.Error != nil {
+		log.Printf("Purge Error (DB Delete Users): %v", res.Error)
+	}
+
+	if len(strips) > 0 || res.RowsAffected > 0 {
+		log.Printf("Purged %d strips and %d users from the trash", len(strips), res.RowsAffected)
+	}
+}
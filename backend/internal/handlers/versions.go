@@ -0,0 +1,187 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"web-photobooth/backend/internal/models"
+)
+
+func (h *Handler) ListStripVersions(c *gin.Context) {
+	strip, ok := h.ownedStrip(c)
+	if !ok {
+		return
+	}
+	h.listStripVersions(c, strip)
+}
+
+func (h *Handler) RestoreStripVersion(c *gin.Context) {
+	strip, ok := h.ownedStrip(c)
+	if !ok {
+		return
+	}
+	h.restoreStripVersion(c, strip)
+}
+
+func (h *Handler) PruneStripVersions(c *gin.Context) {
+	strip, ok := h.ownedStrip(c)
+	if !ok {
+		return
+	}
+	h.pruneStripVersions(c, strip)
+}
+
+func (h *Handler) GuestListStripVersions(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+	h.listStripVersions(c, strip)
+}
+
+func (h *Handler) GuestRestoreStripVersion(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+	h.restoreStripVersion(c, strip)
+}
+
+func (h *Handler) GuestPruneStripVersions(c *gin.Context) {
+	strip, ok := h.guestStripForManage(c)
+	if !ok {
+		return
+	}
+	h.pruneStripVersions(c, strip)
+}
+
+// listStripVersions returns the strip's earlier images, newest first.
+func (h *Handler) listStripVersions(c *gin.Context, strip models.Strip) {
+	var versions []models.StripVersion
+	if err := h.DB.Where("strip_id = ?", strip.ID).Order("created_at DESC").Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+
+	for i := range versions {
+		versions[i].FileURL = h.cdnURL(versions[i].StorageKey)
+	}
+	c.JSON(http.StatusOK, gin.H{"versions": versions})
+}
+
+// restoreStripVersion makes an earlier image current again. The image it
+// replaces becomes a version itself, so a restore can always be undone.
+func (h *Handler) restoreStripVersion(c *gin.Context, strip models.Strip) {
+	versionID := c.Param("versionId")
+
+	var restored models.Strip
+	var replacedKey string
+	err := h.DB.Transaction(func(tx *gorm.DB) error {
+		// 1. Lock the strip so the swap sees its current image
+		if err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).First(&restored, "id = ?", strip.ID).Error; err != nil {
+			return err
+		}
+		replacedKey = storageKey(restored.FileURL)
+
+		var version models.StripVersion
+		if err := tx.Where("id = ? AND strip_id = ?", versionID, strip.ID).First(&version).Error; err != nil {
+			return err
+		}
+
+		// 2. Swap it in; the current image is archived as a version.
+		// The caption is drawn into the image, so it goes back with it
+		updates := map[string]interface{}{
+			"file_url":        h.cdnURL(version.StorageKey),
+			"render_settings": version.RenderSettings,
+		}
+		if version.RenderSettings != nil {
+			updates["caption"] = version.RenderSettings.Caption
+		}
+		if err := swapStripImage(tx, restored, updates); err != nil {
+			return err
+		}
+
+		// 3. The restored image is current now, not a version
+		if err := tx.Delete(&version).Error; err != nil {
+			return err
+		}
+		return tx.First(&restored, "id = ?", strip.ID).Error
+	})
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Version not found"})
+		return
+	}
+	if err != nil {
+		log.Printf("RestoreStripVersion DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to restore version"})
+		return
+	}
+
+	h.deleteDerivedObjects(context.Background(), replacedKey)
+
+	c.JSON(http.StatusOK, gin.H{"message": "Version restored", "strip": restored})
+}
+
+// pruneStripVersions deletes all but the newest ?keep= versions (default 0),
+// storage objects included.
+func (h *Handler) pruneStripVersions(c *gin.Context, strip models.Strip) {
+	keep := 0
+	if raw := c.Query("keep"); raw != "" {
+		n, err := strconv.Atoi(raw)
+		if err != nil || n < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "keep must be a non-negative number"})
+			return
+		}
+		keep = n
+	}
+
+	// 1. Everything past the newest `keep`
+	var versions []models.StripVersion
+	if err := h.DB.Where("strip_id = ?", strip.ID).
+		Order("created_at DESC").
+		Offset(keep).
+		Find(&versions).Error; err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch versions"})
+		return
+	}
+	if len(versions) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "Nothing to prune", "pruned": 0})
+		return
+	}
+
+	// 2. Drop the rows first so nothing points at a deleted object
+	ids := make([]string, 0, len(versions))
+	for _, v := range versions {
+		ids = append(ids, v.ID)
+	}
+	if err := h.DB.Where("id IN ?", ids).Delete(&models.StripVersion{}).Error; err != nil {
+		log.Printf("PruneStripVersions DB Error: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to prune versions"})
+		return
+	}
+
+	// 3. Then the objects
+	for _, v := range versions {
+		h.deleteObject(context.Background(), v.StorageKey)
+		h.deleteDerivedObjects(context.Background(), v.StorageKey)
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Versions pruned", "pruned": len(versions)})
+}
+
+// ownedStrip loads the :id strip if the caller owns it. It writes the error
+// response itself and returns false on failure.
+func (h *Handler) ownedStrip(c *gin.Context) (models.Strip, bool) {
+	var strip models.Strip
+	if err := h.DB.Where("id = ? AND user_id = ?", c.Param("id"), c.GetString("user_id")).First(&strip).Error; err != nil {
+		c.JSON(http.StatusNotFound, gin.H{"error": "Strip not found"})
+		return strip, false
+	}
+	return strip, true
+}
@@ -1,6 +1,7 @@
 package middleware
 
 import (
+	"crypto/subtle"
 	"fmt"
 	"net/http"
 	"strings"
@@ -9,47 +10,113 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
-func AuthMiddleware(jwtSecret string) gin.HandlerFunc {
+// Cookie names used by the optional cookie session mode
+const (
+	SessionCookie = "wuby_session"
+	CSRFCookie    = "wuby_csrf"
+	CSRFHeader    = "X-CSRF-Token"
+)
+
+// AuthMiddleware requires a valid JWT, from the Authorization header or the
+// session cookie. active reports whether the user may still sign in; tokens
+// of soft-deleted users outlive the delete, so it is checked every request.
+func AuthMiddleware(jwtSecret string, active func(userID string) (bool, error)) gin.HandlerFunc {
 	return func(c *gin.Context) {
-		authHeader := c.GetHeader("Authorization")
-		if authHeader == "" {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization header required"})
+		if status, msg := authenticate(c, jwtSecret, active); status != http.StatusOK {
+			c.JSON(status, gin.H{"error": msg})
 			c.Abort()
 			return
 		}
+		c.Next()
+	}
+}
 
-		tokenString := strings.TrimPrefix(authHeader, "Bearer ")
-		token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-			if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-				return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-			}
-			return []byte(jwtSecret), nil
-		})
+// OptionalAuthMiddleware identifies the caller like AuthMiddleware when they
+// send credentials, for public routes that give owners more. Requests without
+// valid credentials go through anonymously.
+func OptionalAuthMiddleware(jwtSecret string, active func(userID string) (bool, error)) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		authenticate(c, jwtSecret, active)
+		c.Next()
+	}
+}
 
-		if err != nil || !token.Valid {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid token"})
-			c.Abort()
-			return
+// authenticate sets user_id and is_admin from the request's credentials. It
+// returns http.StatusOK, or the status and message to reject the request with.
+func authenticate(c *gin.Context, jwtSecret string, active func(userID string) (bool, error)) (int, string) {
+	var tokenString string
+	if authHeader := c.GetHeader("Authorization"); authHeader != "" {
+		tokenString = strings.TrimPrefix(authHeader, "Bearer ")
+	} else if cookie, err := c.Cookie(SessionCookie); err == nil && cookie != "" {
+		// Cookie sessions are sent by the browser automatically, so
+		// state-changing requests must prove they came from our frontend.
+		if !validCSRF(c) {
+			return http.StatusForbidden, "Invalid CSRF token"
 		}
+		tokenString = cookie
+	} else {
+		return http.StatusUnauthorized, "Authorization header required"
+	}
+
+	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
+		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+		}
+		return []byte(jwtSecret), nil
+	})
+
+	if err != nil || !token.Valid {
+		return http.StatusUnauthorized, "Invalid token"
+	}
+
+	claims, ok := token.Claims.(jwt.MapClaims)
+	if !ok {
+		return http.StatusUnauthorized, "Invalid claims"
+	}
+	uid, ok := claims["user_id"].(string)
+	if !ok {
+		fmt.Printf("AUTH ERROR: user_id claim is not a string: %T\n", claims["user_id"])
+		return http.StatusUnauthorized, "Invalid user_id in token"
+	}
+	ok, err = active(uid)
+	if err != nil {
+		return http.StatusInternalServerError, "Failed to check user"
+	}
+	if !ok {
+		return http.StatusUnauthorized, "Account is deleted"
+	}
+	c.Set("user_id", uid)
+
+	isAdmin, _ := claims["is_admin"].(bool) // Default to false if missing or wrong type
+	c.Set("is_admin", isAdmin)
+	return http.StatusOK, ""
+}
 
-		if claims, ok := token.Claims.(jwt.MapClaims); ok {
-			uid, ok := claims["user_id"].(string)
-			if !ok {
-				fmt.Printf("AUTH ERROR: user_id claim is not a string: %T\n", claims["user_id"])
-				c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user_id in token"})
-				c.Abort()
-				return
-			}
-			c.Set("user_id", uid)
-			
-			isAdmin, _ := claims["is_admin"].(bool) // Default to false if missing or wrong type
-			c.Set("is_admin", isAdmin)
-		} else {
-			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid claims"})
+// CSRFMiddleware applies the double-submit check to cookie-session requests
+// on routes that don't need AuthMiddleware, such as logout.
+func CSRFMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if cookie, err := c.Cookie(SessionCookie); err == nil && cookie != "" && !validCSRF(c) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Invalid CSRF token"})
 			c.Abort()
 			return
 		}
-
 		c.Next()
 	}
 }
+
+// validCSRF implements the double-submit check: the X-CSRF-Token header must
+// match the CSRF cookie on every non-safe method.
+func validCSRF(c *gin.Context) bool {
+	switch c.Request.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	}
+
+	cookie, err := c.Cookie(CSRFCookie)
+	if err != nil || cookie == "" {
+		return false
+	}
+	header := c.GetHeader(CSRFHeader)
+	return subtle.ConstantTimeCompare([]byte(cookie), []byte(header)) == 1
+}
@@ -1,6 +1,9 @@
 package models
 
 import (
+	"database/sql/driver"
+	"encoding/json"
+	"fmt"
 	"time"
 
 	"gorm.io/gorm"
@@ -13,6 +16,9 @@ type User struct {
 	Password  string    `json:"-"`
 	IsAdmin   bool      `json:"is_admin" gorm:"default:false"`
 	CreatedAt time.Time `json:"created_at"`
+
+	// Set when the user is in the trash; purged after the retention window
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
 }
 
 type Strip struct {
@@ -25,8 +31,263 @@ type Strip struct {
 	IsGuest   bool       `json:"is_guest"`
 	ExpiresAt *time.Time `json:"expires_at"`
 	CreatedAt time.Time  `json:"created_at"`
+
+	// Short Crockford base32 code used in QR codes and typed URLs (/v/<code>)
+	ShortCode *string `gorm:"uniqueIndex" json:"short_code"`
+
+	// How the strip was composed; nil for strips saved before this was recorded
+	RenderSettings *RenderSettings `gorm:"type:jsonb" json:"render_settings"`
+
+	// Storage keys of the original, unfiltered shots (private objects), kept so
+	// the strip can be re-rendered
+	ShotKeys StringList `gorm:"type:jsonb" json:"-"`
+
+	// How often the image was fetched through the download endpoint
+	DownloadCount int64 `gorm:"not null;default:0" json:"download_count"`
+
+	// Who can open the strip without being its owner; see the Visibility* constants
+	Visibility string `gorm:"not null;default:unlisted;index" json:"visibility"`
+
+	// Set when the strip is in the trash; purged after the retention window
+	DeletedAt gorm.DeletedAt `gorm:"index" json:"deleted_at"`
+
+	// SHA-256 of the secret returned by GuestSaveStrip; required to claim the strip
+	ClaimTokenHash string `json:"-"`
+
+	// SHA-256 of the guest management token (edit/delete/extend without an account)
+	ManageTokenHash string `json:"-"`
+	ExpiryExtended  bool   `json:"expiry_extended" gorm:"default:false"`
+
+	// Loaded separately from strip_tags for listings
+	Tags []Tag `gorm:"-" json:"tags,omitempty"`
+}
+
+// RenderSettings are the photobooth options a strip was rendered with. They
+// are stored as JSONB on the strip.
+type RenderSettings struct {
+	Filter         string `json:"filter"`
+	StripColor     string `json:"strip_color"`
+	Caption        string `json:"caption"`
+	CaptionSize    int    `json:"caption_size"`
+	Font           string `json:"font"`
+	RoundedCorners bool   `json:"rounded_corners"`
+	PhotoCount     int    `json:"photo_count"`
+}
+
+func (s RenderSettings) Value() (driver.Value, error) {
+	return json.Marshal(s)
+}
+
+func (s *RenderSettings) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case []byte:
+		return json.Unmarshal(v, s)
+	case string:
+		return json.Unmarshal([]byte(v), s)
+	default:
+		return fmt.Errorf("unsupported render settings type %T", value)
+	}
+}
+
+// StringList is a []string stored as a JSON array.
+type StringList []string
+
+func (l StringList) Value() (driver.Value, error) {
+	if l == nil {
+		return nil, nil
+	}
+	return json.Marshal([]string(l))
+}
+
+func (l *StringList) Scan(value interface{}) error {
+	switch v := value.(type) {
+	case nil:
+		*l = nil
+		return nil
+	case []byte:
+		return json.Unmarshal(v, (*[]string)(l))
+	case string:
+		return json.Unmarshal([]byte(v), (*[]string)(l))
+	default:
+		return fmt.Errorf("unsupported string list type %T", value)
+	}
+}
+
+// Strip visibility levels. Unlisted strips are served to anyone with the link
+// (the behaviour before visibility existed); public strips are also listed.
+const (
+	VisibilityPrivate  = "private"
+	VisibilityUnlisted = "unlisted"
+	VisibilityPublic   = "public"
+)
+
+// ValidVisibility reports whether v is one of the visibility levels.
+func ValidVisibility(v string) bool {
+	return v == VisibilityPrivate || v == VisibilityUnlisted || v == VisibilityPublic
+}
+
+// MagicLink is a single-use passwordless sign-in token. Only the SHA-256
+// hash of the token is stored.
+type MagicLink struct {
+	ID        string     `gorm:"primaryKey" json:"id"`
+	Email     string     `gorm:"index" json:"email"`
+	TokenHash string     `gorm:"uniqueIndex" json:"-"`
+	ExpiresAt time.Time  `json:"expires_at"`
+	UsedAt    *time.Time `json:"used_at"`
+	CreatedAt time.Time  `gorm:"index" json:"created_at"`
+}
+
+// Album is a user-owned, ordered collection of strips. Setting ShareSlug makes
+// it publicly viewable at /api/albums/public/<slug>.
+type Album struct {
+	ID           string    `gorm:"primaryKey" json:"id"`
+	UserID       string    `gorm:"index;not null" json:"user_id"`
+	User         User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	Name         string    `json:"name"`
+	Description  string    `json:"description"`
+	CoverStripID *string   `json:"cover_strip_id"`
+	CoverStrip   *Strip    `gorm:"foreignKey:CoverStripID;references:ID;constraint:OnDelete:SET NULL" json:"-"`
+	ShareSlug    *string   `gorm:"uniqueIndex" json:"share_slug"`
+	CreatedAt    time.Time `json:"created_at"`
+	UpdatedAt    time.Time `json:"updated_at"`
+}
+
+// AlbumStrip links a strip into an album at a given position.
+type AlbumStrip struct {
+	AlbumID  string    `gorm:"primaryKey" json:"album_id"`
+	Album    Album     `gorm:"foreignKey:AlbumID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	StripID  string    `gorm:"primaryKey;index" json:"strip_id"`
+	Strip    Strip     `gorm:"foreignKey:StripID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	Position int       `json:"position"`
+	AddedAt  time.Time `json:"added_at"`
+}
+
+// Tag is a user's label for their strips. Slug is the normalized lowercase
+// form and is unique per user; Name keeps the spelling first used.
+type Tag struct {
+	ID        string    `gorm:"primaryKey" json:"id"`
+	UserID    string    `gorm:"uniqueIndex:idx_tags_user_slug;not null" json:"-"`
+	User      User      `gorm:"foreignKey:UserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	Name      string    `json:"name"`
+	Slug      string    `gorm:"uniqueIndex:idx_tags_user_slug;index;not null" json:"slug"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// StripTag links a tag to a strip.
+type StripTag struct {
+	StripID   string `gorm:"primaryKey"`
+	Strip     Strip  `gorm:"foreignKey:StripID;references:ID;constraint:OnDelete:CASCADE"`
+	TagID     string `gorm:"primaryKey;index"`
+	Tag       Tag    `gorm:"foreignKey:TagID;references:ID;constraint:OnDelete:CASCADE"`
+	CreatedAt time.Time
+}
+
+// ShareLink grants access to one strip through a random slug, whatever the
+// strip's visibility. Password, expiry and view limit are all optional.
+type ShareLink struct {
+	ID           string     `gorm:"primaryKey" json:"id"`
+	StripID      string     `gorm:"index;not null" json:"strip_id"`
+	Strip        Strip      `gorm:"foreignKey:StripID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	UserID       string     `gorm:"index;not null" json:"-"`
+	Slug         string     `gorm:"uniqueIndex;not null" json:"slug"`
+	PasswordHash string     `json:"-"` // bcrypt; empty when the link has no password
+	ExpiresAt    *time.Time `json:"expires_at"`
+	MaxViews     *int       `json:"max_views"`
+	ViewCount    int        `gorm:"not null;default:0" json:"view_count"`
+	RevokedAt    *time.Time `json:"revoked_at"`
+	CreatedAt    time.Time  `json:"created_at"`
+
+	// Password guesses in the current window, which ends at PasswordWindowEnd
+	PasswordAttempts  int        `gorm:"not null;default:0" json:"-"`
+	PasswordWindowEnd *time.Time `json:"-"`
+}
+
+// StripVersion is an earlier image of a strip, kept when the image is
+// replaced or re-rendered so it can be restored.
+type StripVersion struct {
+	ID             string          `gorm:"primaryKey" json:"id"`
+	StripID        string          `gorm:"index;not null" json:"strip_id"`
+	Strip          Strip           `gorm:"foreignKey:StripID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	StorageKey     string          `gorm:"not null" json:"-"`
+	RenderSettings *RenderSettings `gorm:"type:jsonb" json:"render_settings"`
+	CreatedAt      time.Time       `gorm:"index" json:"created_at"` // When this image was superseded
+
+	// CDN URL of StorageKey, filled in for responses
+	FileURL string `gorm:"-" json:"file_url"`
+}
+
+// Strip transfer offer states.
+const (
+	TransferPending   = "pending"
+	TransferAccepted  = "accepted"
+	TransferDeclined  = "declined"
+	TransferCancelled = "cancelled"
+)
+
+// StripTransfer is an offer from a strip's owner to hand it to another user.
+// Ownership only changes once the recipient accepts.
+type StripTransfer struct {
+	ID          string     `gorm:"primaryKey" json:"id"`
+	StripID     string     `gorm:"index;not null" json:"strip_id"`
+	Strip       Strip      `gorm:"foreignKey:StripID;references:ID;constraint:OnDelete:CASCADE" json:"strip"`
+	FromUserID  string     `gorm:"index;not null" json:"from_user_id"`
+	FromUser    User       `gorm:"foreignKey:FromUserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	ToUserID    string     `gorm:"index;not null" json:"to_user_id"`
+	ToUser      User       `gorm:"foreignKey:ToUserID;references:ID;constraint:OnDelete:CASCADE" json:"-"`
+	Status      string     `gorm:"not null;default:pending;index" json:"status"`
+	ExpiresAt   time.Time  `json:"expires_at"`
+	RespondedAt *time.Time `json:"responded_at"`
+	CreatedAt   time.Time  `json:"created_at"`
+}
+
+// AuditLog records sensitive changes. Rows are kept when the users or strips
+// they mention are deleted, so there are no foreign keys.
+type AuditLog struct {
+	ID         string    `gorm:"primaryKey" json:"id"`
+	ActorID    string    `gorm:"index;not null" json:"actor_id"` // Who made the change
+	Action     string    `gorm:"index;not null" json:"action"`
+	StripID    *string   `gorm:"index" json:"strip_id"`
+	FromUserID *string   `json:"from_user_id"`
+	ToUserID   *string   `json:"to_user_id"`
+	CreatedAt  time.Time `gorm:"index" json:"created_at"`
+}
+
+// Audit actions.
+const (
+	AuditStripTransfer      = "strip.transfer"
+	AuditStripForceTransfer = "strip.force_transfer"
+)
+
+// IdempotencyKey records the outcome of a request sent with an
+// Idempotency-Key header so that retries replay the original response.
+type IdempotencyKey struct {
+	Key          string `gorm:"primaryKey"`
+	Scope        string `gorm:"primaryKey"` // route + caller, so keys can't collide across users
+	Fingerprint  string // SHA-256 of method, path and body
+	StatusCode   int
+	ResponseBody []byte
+	Completed    bool
+	CreatedAt    time.Time `gorm:"index"`
 }
 
 func Migrate(db *gorm.DB) error {
-	return db.AutoMigrate(&User{}, &Strip{})
+	if err := db.AutoMigrate(&User{}, &Strip{}, &MagicLink{}, &IdempotencyKey{}, &Album{}, &AlbumStrip{}, &Tag{}, &StripTag{}, &ShareLink{}, &StripVersion{}, &StripTransfer{}, &AuditLog{}); err != nil {
+		return err
+	}
+	return migrateStripSearch(db)
+}
+
+// migrateStripSearch adds the generated strips.search_vector column (not
+// mapped on Strip, it is only used in queries) and its GIN index.
+// The 'simple' config is used because titles and captions are short, often
+// names, and written in many languages, so English stemming does more harm than good.
+func migrateStripSearch(db *gorm.DB) error {
+	if err := db.Exec(`ALTER TABLE strips ADD COLUMN IF NOT EXISTS search_vector tsvector
+		GENERATED ALWAYS AS (
+			setweight(to_tsvector('simple', coalesce(title, '')), 'A') ||
+			setweight(to_tsvector('simple', coalesce(caption, '')), 'B')
+		) STORED`).Error; err != nil {
+		return err
+	}
+	return db.Exec(`CREATE INDEX IF NOT EXISTS idx_strips_search_vector ON strips USING GIN (search_vector)`).Error
 }
@@ -0,0 +1,146 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+	"golang.org/x/crypto/bcrypt"
+	"web-photobooth/backend/internal/config"
+)
+
+var ErrUnsupportedHash = errors.New("unsupported password hash format")
+
+// Hasher hashes new passwords and verifies stored ones. Verify reports
+// needsRehash when the stored hash uses an outdated algorithm or parameters.
+type Hasher interface {
+	Hash(password string) (string, error)
+	Verify(password, encoded string) (ok bool, needsRehash bool, err error)
+}
+
+// Argon2id produces PHC-formatted argon2id hashes, e.g.
+// $argon2id$v=19$m=65536,t=3,p=2$<salt>$<key>. Legacy bcrypt hashes are still
+// accepted by Verify and always flagged for rehash.
+type Argon2id struct {
+	Memory      uint32 // KiB
+	Iterations  uint32
+	Parallelism uint8
+	SaltLength  uint32
+	KeyLength   uint32
+}
+
+// Bounds for the configurable Argon2id parameters. argon2.IDKey panics on
+// zero iterations or parallelism, and memory is capped to keep one login
+// from exhausting the server.
+const (
+	maxArgon2MemoryKB    = 4 * 1024 * 1024 // 4 GiB
+	maxArgon2Iterations  = 100
+	maxArgon2Parallelism = 255
+)
+
+// NewHasher builds the hasher from config, rejecting parameters that would
+// make every hash panic or misbehave.
+func NewHasher(cfg *config.Config) (*Argon2id, error) {
+	if cfg.Argon2Parallelism < 1 || cfg.Argon2Parallelism > maxArgon2Parallelism {
+		return nil, fmt.Errorf("ARGON2_PARALLELISM must be between 1 and %d, got %d", maxArgon2Parallelism, cfg.Argon2Parallelism)
+	}
+	if cfg.Argon2Iterations < 1 || cfg.Argon2Iterations > maxArgon2Iterations {
+		return nil, fmt.Errorf("ARGON2_ITERATIONS must be between 1 and %d, got %d", maxArgon2Iterations, cfg.Argon2Iterations)
+	}
+	// Argon2 needs at least 8 KiB per lane
+	if minMemory := 8 * cfg.Argon2Parallelism; cfg.Argon2MemoryKB < minMemory || cfg.Argon2MemoryKB > maxArgon2MemoryKB {
+		return nil, fmt.Errorf("ARGON2_MEMORY_KB must be between %d and %d, got %d", minMemory, maxArgon2MemoryKB, cfg.Argon2MemoryKB)
+	}
+
+	return &Argon2id{
+		Memory:      uint32(cfg.Argon2MemoryKB),
+		Iterations:  uint32(cfg.Argon2Iterations),
+		Parallelism: uint8(cfg.Argon2Parallelism),
+		SaltLength:  16,
+		KeyLength:   32,
+	}, nil
+}
+
+func (a *Argon2id) Hash(password string) (string, error) {
+	salt := make([]byte, a.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+
+	key := argon2.IDKey([]byte(password), salt, a.Iterations, a.Memory, a.Parallelism, a.KeyLength)
+
+	return fmt.Sprintf("$argon2id$v=%d$m=%d,t=%d,p=%d$%s$%s",
+		argon2.Version, a.Memory, a.Iterations, a.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt),
+		base64.RawStdEncoding.EncodeToString(key),
+	), nil
+}
+
+func (a *Argon2id) Verify(password, encoded string) (bool, bool, error) {
+	switch {
+	case encoded == "":
+		// Passwordless accounts (e.g. created via magic link)
+		return false, false, nil
+	case strings.HasPrefix(encoded, "$argon2id$"):
+		return a.verifyArgon2id(password, encoded)
+	case strings.HasPrefix(encoded, "$2a$"), strings.HasPrefix(encoded, "$2b$"), strings.HasPrefix(encoded, "$2y$"):
+		if err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password)); err != nil {
+			if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+				return false, false, nil
+			}
+			return false, false, err
+		}
+		return true, true, nil
+	default:
+		return false, false, ErrUnsupportedHash
+	}
+}
+
+func (a *Argon2id) verifyArgon2id(password, encoded string) (bool, bool, error) {
+	// "", "argon2id", "v=19", "m=..,t=..,p=..", salt, key
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 {
+		return false, false, ErrUnsupportedHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil {
+		return false, false, ErrUnsupportedHash
+	}
+
+	var memory, iterations uint32
+	var parallelism uint8
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &memory, &iterations, &parallelism); err != nil {
+		return false, false, ErrUnsupportedHash
+	}
+	// Out-of-range parameters would panic in argon2 or run unbounded
+	if iterations < 1 || iterations > maxArgon2Iterations || parallelism < 1 || memory > maxArgon2MemoryKB {
+		return false, false, ErrUnsupportedHash
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return false, false, ErrUnsupportedHash
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return false, false, ErrUnsupportedHash
+	}
+
+	other := argon2.IDKey([]byte(password), salt, iterations, memory, parallelism, uint32(len(key)))
+	if subtle.ConstantTimeCompare(key, other) != 1 {
+		return false, false, nil
+	}
+
+	needsRehash := version != argon2.Version ||
+		memory != a.Memory ||
+		iterations != a.Iterations ||
+		parallelism != a.Parallelism ||
+		uint32(len(salt)) != a.SaltLength ||
+		uint32(len(key)) != a.KeyLength
+	return true, needsRehash, nil
+}
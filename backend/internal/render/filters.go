@@ -0,0 +1,114 @@
+package render
+
+import (
+	"fmt"
+	"image"
+	"math"
+	"math/rand"
+)
+
+// applyFilter runs one of the named photobooth filters over img in place.
+// Each step ports the matching glfx shader used by glfxFilters.ts; seed keeps
+// the film grain stable between renders of the same strip.
+func applyFilter(img *image.RGBA, name string, seed int64) error {
+	var steps []func(r, g, b, u, v float64) (float64, float64, float64)
+	switch name {
+	case "none":
+		return nil
+	case "cinematic":
+		steps = append(steps, brightnessContrast(0, 0.15), vignette(0.4, 0.6), hueSaturation(0, -0.15))
+	case "film":
+		rng := rand.New(rand.NewSource(seed))
+		steps = append(steps, sepia(0.3), noise(0.08, rng), brightnessContrast(-0.05, 0.12))
+	case "warm":
+		steps = append(steps, hueSaturation(0.05, 0.1), brightnessContrast(0.05, 0.1))
+	case "bw":
+		steps = append(steps, hueSaturation(0, -1), brightnessContrast(0, 0.2))
+	default:
+		return fmt.Errorf("unknown filter %q", name)
+	}
+
+	b := img.Bounds()
+	w, h := float64(b.Dx()), float64(b.Dy())
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			i := img.PixOffset(x, y)
+			r := float64(img.Pix[i]) / 255
+			g := float64(img.Pix[i+1]) / 255
+			bl := float64(img.Pix[i+2]) / 255
+			u, v := (float64(x-b.Min.X)+0.5)/w, (float64(y-b.Min.Y)+0.5)/h
+			for _, step := range steps {
+				r, g, bl = step(r, g, bl, u, v)
+			}
+			img.Pix[i] = toByte(r)
+			img.Pix[i+1] = toByte(g)
+			img.Pix[i+2] = toByte(bl)
+		}
+	}
+	return nil
+}
+
+func brightnessContrast(brightness, contrast float64) func(r, g, b, u, v float64) (float64, float64, float64) {
+	adjust := func(c float64) float64 {
+		c += brightness
+		if contrast > 0 {
+			return (c-0.5)/(1-contrast) + 0.5
+		}
+		return (c-0.5)*(1+contrast) + 0.5
+	}
+	return func(r, g, b, _, _ float64) (float64, float64, float64) {
+		return adjust(r), adjust(g), adjust(b)
+	}
+}
+
+func hueSaturation(hue, saturation float64) func(r, g, b, u, v float64) (float64, float64, float64) {
+	angle := hue * math.Pi
+	s, c := math.Sin(angle), math.Cos(angle)
+	wx := (2*c + 1) / 3
+	wy := (-math.Sqrt(3)*s - c + 1) / 3
+	wz := (math.Sqrt(3)*s - c + 1) / 3
+
+	return func(r, g, b, _, _ float64) (float64, float64, float64) {
+		r, g, b = r*wx+g*wy+b*wz, r*wz+g*wx+b*wy, r*wy+g*wz+b*wx
+
+		avg := (r + g + b) / 3
+		if saturation > 0 {
+			k := 1 - 1/(1.001-saturation)
+			return r + (avg-r)*k, g + (avg-g)*k, b + (avg-b)*k
+		}
+		k := -saturation
+		return r + (avg-r)*k, g + (avg-g)*k, b + (avg-b)*k
+	}
+}
+
+func sepia(amount float64) func(r, g, b, u, v float64) (float64, float64, float64) {
+	return func(r, g, b, _, _ float64) (float64, float64, float64) {
+		return math.Min(1, r*(1-0.607*amount)+g*0.769*amount+b*0.189*amount),
+			math.Min(1, r*0.349*amount+g*(1-0.314*amount)+b*0.168*amount),
+			math.Min(1, r*0.272*amount+g*0.534*amount+b*(1-0.869*amount))
+	}
+}
+
+func vignette(size, amount float64) func(r, g, b, u, v float64) (float64, float64, float64) {
+	return func(r, g, b, u, v float64) (float64, float64, float64) {
+		dist := math.Hypot(u-0.5, v-0.5)
+		k := smoothstep(0.8, size*0.799, dist*(amount+size))
+		return r * k, g * k, b * k
+	}
+}
+
+func noise(amount float64, rng *rand.Rand) func(r, g, b, u, v float64) (float64, float64, float64) {
+	return func(r, g, b, _, _ float64) (float64, float64, float64) {
+		n := (rng.Float64() - 0.5) * amount
+		return r + n, g + n, b + n
+	}
+}
+
+func smoothstep(edge0, edge1, x float64) float64 {
+	t := math.Min(math.Max((x-edge0)/(edge1-edge0), 0), 1)
+	return t * t * (3 - 2*t)
+}
+
+func toByte(c float64) uint8 {
+	return uint8(math.Round(math.Min(math.Max(c, 0), 1) * 255))
+}
@@ -0,0 +1,123 @@
+package render
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/math/fixed"
+)
+
+// Social card layout: the strip stands on the left, branding and title on the
+// right, over the app's lavender background.
+const (
+	OGWidth  = 1200
+	OGHeight = 630
+
+	ogPadding     = 48
+	ogStripLeft   = 120
+	ogTextLeft    = 480
+	ogLogoWidth   = 320
+	ogTitleSize   = 52
+	ogTaglineSize = 26
+	ogShadow      = 14
+)
+
+var (
+	ogTop    = color.RGBA{0xf8, 0xf2, 0xff, 0xff} // --background in the frontend
+	ogBottom = color.RGBA{0xe9, 0xd5, 0xff, 0xff}
+)
+
+// OGCard composes a 1200x630 link preview for a strip.
+func OGCard(strip image.Image, title string) (image.Image, error) {
+	canvas := image.NewRGBA(image.Rect(0, 0, OGWidth, OGHeight))
+
+	// 1. Vertical gradient background
+	for y := 0; y < OGHeight; y++ {
+		t := float64(y) / float64(OGHeight-1)
+		c := color.RGBA{
+			R: lerp(ogTop.R, ogBottom.R, t),
+			G: lerp(ogTop.G, ogBottom.G, t),
+			B: lerp(ogTop.B, ogBottom.B, t),
+			A: 0xff,
+		}
+		xdraw.Draw(canvas, image.Rect(0, y, OGWidth, y+1), &image.Uniform{c}, image.Point{}, xdraw.Src)
+	}
+
+	// 2. The strip, scaled to the card's height, over a soft shadow
+	sb := strip.Bounds()
+	h := OGHeight - 2*ogPadding
+	w := int(math.Round(float64(sb.Dx()) * float64(h) / float64(sb.Dy())))
+	if maxW := ogTextLeft - ogStripLeft - ogPadding; w > maxW {
+		w = maxW
+		h = int(math.Round(float64(sb.Dy()) * float64(w) / float64(sb.Dx())))
+	}
+	x := ogStripLeft + (ogTextLeft-ogStripLeft-ogPadding-w)/2
+	y := (OGHeight - h) / 2
+	dst := image.Rect(x, y, x+w, y+h)
+	for i := ogShadow; i > 0; i-- {
+		shade := &image.Uniform{color.NRGBA{0x58, 0x1c, 0x87, uint8(ogShadow - i + 1)}}
+		xdraw.Draw(canvas, dst.Add(image.Pt(0, ogShadow/2)).Inset(-i), shade, image.Point{}, xdraw.Over)
+	}
+	xdraw.CatmullRom.Scale(canvas, dst, strip, sb, xdraw.Over, nil)
+
+	// 3. Logo
+	logo, err := png.Decode(bytes.NewReader(logoPNG))
+	if err != nil {
+		return nil, fmt.Errorf("decode logo: %w", err)
+	}
+	lb := logo.Bounds()
+	logoH := int(math.Round(float64(lb.Dy()) * ogLogoWidth / float64(lb.Dx())))
+	logoTop := 170
+	xdraw.CatmullRom.Scale(canvas, image.Rect(ogTextLeft, logoTop, ogTextLeft+ogLogoWidth, logoTop+logoH), logo, lb, xdraw.Over, nil)
+
+	// 4. Title and tagline
+	textWidth := OGWidth - ogTextLeft - ogPadding
+	baseline := logoTop + logoH + 80
+	if title != "" {
+		if err := drawLeft(canvas, gobold.TTF, ogTitleSize, captionColor, title, ogTextLeft, baseline, textWidth); err != nil {
+			return nil, err
+		}
+		baseline += 56
+	}
+	if err := drawLeft(canvas, goregular.TTF, ogTaglineSize, timestampColor, "A digital photobooth memory", ogTextLeft, baseline, textWidth); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+// drawLeft draws one line of text starting at x with its baseline at y,
+// cutting it short with an ellipsis if it is wider than maxWidth.
+func drawLeft(dst *image.RGBA, ttf []byte, sizePx float64, c color.Color, text string, x, y, maxWidth int) error {
+	face, err := newFace(ttf, sizePx)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	limit := fixed.I(maxWidth)
+	if d.MeasureString(text) > limit {
+		runes := []rune(text)
+		for len(runes) > 0 && d.MeasureString(string(runes)+"…") > limit {
+			runes = runes[:len(runes)-1]
+		}
+		text = string(runes) + "…"
+	}
+
+	d.Dot = fixed.P(x, y)
+	d.DrawString(text)
+	return nil
+}
+
+func lerp(a, b uint8, t float64) uint8 {
+	return uint8(math.Round(float64(a) + (float64(b)-float64(a))*t))
+}
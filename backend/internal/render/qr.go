@@ -0,0 +1,150 @@
+package render
+
+import (
+	"bytes"
+	"encoding/base64"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+
+	"github.com/skip2/go-qrcode"
+	xdraw "golang.org/x/image/draw"
+)
+
+// QROptions control a standalone QR code.
+type QROptions struct {
+	Size   int    // Width and height in pixels (SVG: viewBox units)
+	Margin int    // Quiet zone, in modules
+	Level  string // Error correction: L, M, Q or H
+	Logo   bool   // Put the Wuby logo in the middle; needs level Q or H
+}
+
+var qrLevels = map[string]qrcode.RecoveryLevel{
+	"L": qrcode.Low,
+	"M": qrcode.Medium,
+	"Q": qrcode.High, // go-qrcode calls Q "High"
+	"H": qrcode.Highest,
+}
+
+// logoWidthShare is how much of the code's width the logo may cover. At
+// the logo's aspect ratio this hides about 3% of the modules, well within
+// what Q and H recover.
+const logoWidthShare = 0.3
+
+// qrModules encodes content and returns its module matrix, without border.
+func qrModules(content string, opts QROptions) ([][]bool, error) {
+	level, ok := qrLevels[opts.Level]
+	if !ok {
+		return nil, fmt.Errorf("unknown error correction level %q", opts.Level)
+	}
+	if opts.Logo && level < qrcode.High {
+		return nil, fmt.Errorf("a logo needs error correction level Q or H")
+	}
+
+	q, err := qrcode.New(content, level)
+	if err != nil {
+		return nil, fmt.Errorf("qr code: %w", err)
+	}
+	q.DisableBorder = true
+	return q.Bitmap(), nil
+}
+
+// QR draws content as a QR code image of opts.Size pixels.
+func QR(content string, opts QROptions) (image.Image, error) {
+	modules, err := qrModules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+	n := len(modules)
+	total := n + 2*opts.Margin
+
+	// 1. Modules; each pixel takes the module it falls in, which keeps edges
+	// crisp even when the size isn't a multiple of the module count
+	dst := image.NewRGBA(image.Rect(0, 0, opts.Size, opts.Size))
+	for y := 0; y < opts.Size; y++ {
+		my := y*total/opts.Size - opts.Margin
+		for x := 0; x < opts.Size; x++ {
+			mx := x*total/opts.Size - opts.Margin
+			c := color.RGBA{0xff, 0xff, 0xff, 0xff}
+			if mx >= 0 && my >= 0 && mx < n && my < n && modules[my][mx] {
+				c = color.RGBA{0, 0, 0, 0xff}
+			}
+			dst.SetRGBA(x, y, c)
+		}
+	}
+
+	// 2. Logo on a white backdrop
+	if opts.Logo {
+		logo, err := png.Decode(bytes.NewReader(logoPNG))
+		if err != nil {
+			return nil, fmt.Errorf("decode logo: %w", err)
+		}
+		box := logoBox(float64(opts.Size), float64(n)/float64(total), logo.Bounds())
+		xdraw.Draw(dst, box.Inset(-int(math.Ceil(float64(opts.Size)/float64(total)))), image.White, image.Point{}, xdraw.Src)
+		xdraw.CatmullRom.Scale(dst, box, logo, logo.Bounds(), xdraw.Over, nil)
+	}
+
+	return dst, nil
+}
+
+// QRSVG draws content as a QR code in SVG.
+func QRSVG(content string, opts QROptions) ([]byte, error) {
+	modules, err := qrModules(content, opts)
+	if err != nil {
+		return nil, err
+	}
+	n := len(modules)
+	total := n + 2*opts.Margin
+
+	// 1. One path for all dark modules, merging horizontal runs
+	var d strings.Builder
+	for y, row := range modules {
+		for x := 0; x < n; {
+			if !row[x] {
+				x++
+				continue
+			}
+			start := x
+			for x < n && row[x] {
+				x++
+			}
+			fmt.Fprintf(&d, "M%d %dh%dv1h-%dz", start+opts.Margin, y+opts.Margin, x-start, x-start)
+		}
+	}
+
+	var b bytes.Buffer
+	fmt.Fprintf(&b, `<svg xmlns="http://www.w3.org/2000/svg" width="%d" height="%d" viewBox="0 0 %d %d" shape-rendering="crispEdges">`, opts.Size, opts.Size, total, total)
+	fmt.Fprintf(&b, `<rect width="%d" height="%d" fill="#fff"/><path d="%s" fill="#000"/>`, total, total, d.String())
+
+	// 2. Logo, in module units
+	if opts.Logo {
+		logo, err := png.DecodeConfig(bytes.NewReader(logoPNG))
+		if err != nil {
+			return nil, fmt.Errorf("decode logo: %w", err)
+		}
+		box := logoBoxF(float64(total), float64(n)/float64(total), float64(logo.Width), float64(logo.Height))
+		fmt.Fprintf(&b, `<rect x="%.2f" y="%.2f" width="%.2f" height="%.2f" fill="#fff"/>`, box[0]-1, box[1]-1, box[2]+2, box[3]+2)
+		fmt.Fprintf(&b, `<image x="%.2f" y="%.2f" width="%.2f" height="%.2f" href="data:image/png;base64,%s"/>`,
+			box[0], box[1], box[2], box[3], base64.StdEncoding.EncodeToString(logoPNG))
+	}
+
+	b.WriteString(`</svg>`)
+	return b.Bytes(), nil
+}
+
+// logoBoxF centers the logo in a code of the given size, as x, y, w, h.
+// codeShare is the part of the size taken by modules rather than margin.
+func logoBoxF(size, codeShare, logoW, logoH float64) [4]float64 {
+	w := size * codeShare * logoWidthShare
+	h := w * logoH / logoW
+	return [4]float64{(size - w) / 2, (size - h) / 2, w, h}
+}
+
+func logoBox(size, codeShare float64, logo image.Rectangle) image.Rectangle {
+	b := logoBoxF(size, codeShare, float64(logo.Dx()), float64(logo.Dy()))
+	x, y := int(math.Round(b[0])), int(math.Round(b[1]))
+	return image.Rect(x, y, x+int(math.Round(b[2])), y+int(math.Round(b[3])))
+}
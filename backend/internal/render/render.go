@@ -0,0 +1,247 @@
+// Package render composes photobooth strips on the server. It mirrors the
+// browser renderer in web/src/lib/utils/renderStrip.ts and the preview page so
+// a re-rendered strip matches the layout of the original: branding row with
+// logo and QR code, square photos, then timestamp and caption.
+package render
+
+import (
+	"bytes"
+	_ "embed"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/png"
+	"math"
+	"strings"
+	"time"
+
+	xdraw "golang.org/x/image/draw"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/gobold"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"web-photobooth/backend/internal/models"
+)
+
+//go:embed assets/wuby_logo.png
+var logoPNG []byte
+
+// Layout constants, in pixels at the 300 DPI the frontend renders at. See
+// stripLayout.ts and preview/settings.ts.
+const (
+	dpi            = 300
+	stripWidthPx   = 600 // 2in
+	sideMarginPx   = 30  // 0.1in
+	contentWidthPx = stripWidthPx - 2*sideMarginPx
+	gapPx          = 30 // 0.1in
+	cornerRadiusPx = 15 // dpi * 0.05
+
+	brandGapPx     = 50
+	brandTopPx     = 10
+	brandBotPx     = 10
+	maxBrandHeight = 200
+
+	timestampTopPx   = 40
+	timestampHeight  = 20
+	timestampBotPx   = 20
+	timestampSizePx  = 12
+	timestampSpacing = 2
+	captionBotPx     = 40
+)
+
+var (
+	captionColor   = color.RGBA{0x58, 0x1c, 0x87, 0xff}
+	timestampColor = color.RGBA{0xa8, 0x55, 0xf7, 0xff}
+)
+
+// Options carry what the strip shows besides the photos and settings.
+type Options struct {
+	// ShareURL is encoded in the QR code; empty leaves the slot blank
+	ShareURL string
+	// Timestamp is printed under the photos, in its own location
+	Timestamp time.Time
+}
+
+// Strip renders shots with the given settings. Shots are center-cropped to
+// squares; there must be exactly settings.PhotoCount of them.
+//
+// The browser draws captions in the chosen web font. Those font files aren't
+// shipped with the server, so captions are set in Go Regular instead.
+func Strip(shots []image.Image, settings models.RenderSettings, opts Options) (image.Image, error) {
+	if len(shots) == 0 || len(shots) != settings.PhotoCount {
+		return nil, fmt.Errorf("need %d shots, got %d", settings.PhotoCount, len(shots))
+	}
+
+	logo, err := png.Decode(bytes.NewReader(logoPNG))
+	if err != nil {
+		return nil, fmt.Errorf("decode logo: %w", err)
+	}
+	bg, err := parseHexColor(settings.StripColor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 1. Layout, exactly as the preview page computes it
+	logoRatio := float64(logo.Bounds().Dx()) / float64(logo.Bounds().Dy())
+	brandHeight := math.Min(float64(contentWidthPx-brandGapPx)/(logoRatio+1), maxBrandHeight)
+	topPx := brandTopPx + brandHeight + brandBotPx
+	captionH := float64(settings.CaptionSize) * 1.2
+	bottomPx := timestampTopPx + timestampHeight + timestampBotPx + captionH + captionBotPx
+
+	n := len(shots)
+	photosPx := contentWidthPx*n + gapPx*(n-1)
+	height := int(math.Round(topPx)) + photosPx + int(math.Round(bottomPx))
+
+	canvas := image.NewRGBA(image.Rect(0, 0, stripWidthPx, height))
+	xdraw.Draw(canvas, canvas.Bounds(), &image.Uniform{bg}, image.Point{}, xdraw.Src)
+
+	// 2. Photos
+	y := int(math.Round(topPx))
+	for i, shot := range shots {
+		photo := squareCrop(shot, contentWidthPx)
+		if err := applyFilter(photo, settings.Filter, int64(i)); err != nil {
+			return nil, err
+		}
+		dst := image.Rect(sideMarginPx, y, sideMarginPx+contentWidthPx, y+contentWidthPx)
+		if settings.RoundedCorners {
+			xdraw.DrawMask(canvas, dst, photo, image.Point{}, roundedMask(contentWidthPx, cornerRadiusPx), image.Point{}, xdraw.Over)
+		} else {
+			xdraw.Draw(canvas, dst, photo, image.Point{}, xdraw.Over)
+		}
+		y += contentWidthPx + gapPx
+	}
+
+	// 3. Branding row: logo on the left, QR code on the right
+	logoW := int(math.Round(logoRatio * brandHeight))
+	logoRect := image.Rect(sideMarginPx, brandTopPx, sideMarginPx+logoW, brandTopPx+int(math.Round(brandHeight)))
+	xdraw.CatmullRom.Scale(canvas, logoRect, logo, logo.Bounds(), xdraw.Over, nil)
+
+	if opts.ShareURL != "" {
+		qrSize := int(math.Round(brandHeight * 0.6))
+		qr, err := qrCode(opts.ShareURL, qrSize)
+		if err != nil {
+			return nil, err
+		}
+		qrX := sideMarginPx + contentWidthPx - qrSize
+		qrY := brandTopPx + int(math.Round((brandHeight-float64(qrSize))/2))
+		xdraw.Draw(canvas, image.Rect(qrX, qrY, qrX+qrSize, qrY+qrSize), qr, image.Point{}, xdraw.Over)
+	}
+
+	// 4. Timestamp and caption
+	lastPhotoBottom := int(math.Round(topPx)) + photosPx
+	timestampY := lastPhotoBottom + timestampTopPx
+	stamp := strings.ToUpper(opts.Timestamp.Format("Jan 02, 2006")) + " • " + opts.Timestamp.Format("03:04 PM")
+	if err := drawCentered(canvas, gobold.TTF, timestampSizePx, timestampSpacing, timestampColor, stamp, timestampY+10); err != nil {
+		return nil, err
+	}
+
+	captionY := timestampY + timestampHeight + timestampBotPx
+	caption := settings.Caption
+	if caption == "" {
+		caption = " "
+	}
+	baseline := captionY + int(math.Round(float64(settings.CaptionSize)*0.8))
+	if err := drawCentered(canvas, goregular.TTF, float64(settings.CaptionSize), 0, captionColor, caption, baseline); err != nil {
+		return nil, err
+	}
+
+	return canvas, nil
+}
+
+// EncodePNG renders img as PNG bytes.
+func EncodePNG(img image.Image) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// Resize scales img to the given width, keeping its aspect ratio.
+func Resize(img image.Image, width int) image.Image {
+	b := img.Bounds()
+	height := max(1, int(math.Round(float64(b.Dy())*float64(width)/float64(b.Dx()))))
+	dst := image.NewRGBA(image.Rect(0, 0, width, height))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), img, b, xdraw.Src, nil)
+	return dst
+}
+
+// squareCrop center-crops src to a square and scales it to size x size.
+func squareCrop(src image.Image, size int) *image.RGBA {
+	b := src.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(b.Min.X+(b.Dx()-side)/2, b.Min.Y+(b.Dy()-side)/2))
+
+	dst := image.NewRGBA(image.Rect(0, 0, size, size))
+	xdraw.CatmullRom.Scale(dst, dst.Bounds(), src, crop, xdraw.Src, nil)
+	return dst
+}
+
+// roundedMask is an alpha mask of a size x size square with rounded corners.
+func roundedMask(size, radius int) *image.Alpha {
+	mask := image.NewAlpha(image.Rect(0, 0, size, size))
+	r := float64(radius)
+	for y := 0; y < size; y++ {
+		for x := 0; x < size; x++ {
+			// Distance into the nearest corner's circle, if in a corner at all
+			cx := math.Max(math.Max(r-float64(x)-0.5, float64(x)+0.5-float64(size)+r), 0)
+			cy := math.Max(math.Max(r-float64(y)-0.5, float64(y)+0.5-float64(size)+r), 0)
+			d := math.Hypot(cx, cy)
+			a := math.Min(math.Max(r-d+0.5, 0), 1) // 1px anti-aliased edge
+			if cx == 0 || cy == 0 {
+				a = 1
+			}
+			mask.SetAlpha(x, y, color.Alpha{A: uint8(a * 255)})
+		}
+	}
+	return mask
+}
+
+// qrCode is the code in the branding row: medium error correction and a
+// one-module quiet zone, like the frontend's margin: 1.
+func qrCode(content string, size int) (image.Image, error) {
+	return QR(content, QROptions{Size: size, Margin: 1, Level: "M"})
+}
+
+// drawCentered draws text centered on the canvas with its baseline at y.
+// spacing adds extra pixels after every character, like canvas letterSpacing.
+func drawCentered(dst *image.RGBA, ttf []byte, sizePx, spacing float64, c color.Color, text string, y int) error {
+	face, err := newFace(ttf, sizePx)
+	if err != nil {
+		return err
+	}
+	defer face.Close()
+
+	d := &font.Drawer{Dst: dst, Src: image.NewUniform(c), Face: face}
+	extra := fixed.Int26_6(spacing * 64)
+	width := d.MeasureString(text) + extra*fixed.Int26_6(len([]rune(text)))
+
+	d.Dot = fixed.Point26_6{X: (fixed.I(dst.Bounds().Dx()) - width) / 2, Y: fixed.I(y)}
+	for _, r := range text {
+		d.DrawString(string(r))
+		d.Dot.X += extra
+	}
+	return nil
+}
+
+// newFace loads a TrueType font at a pixel size.
+func newFace(ttf []byte, sizePx float64) (font.Face, error) {
+	f, err := opentype.Parse(ttf)
+	if err != nil {
+		return nil, err
+	}
+	return opentype.NewFace(f, &opentype.FaceOptions{Size: sizePx, DPI: 72, Hinting: font.HintingFull})
+}
+
+func parseHexColor(s string) (color.RGBA, error) {
+	var r, g, b uint8
+	if len(s) != 7 || s[0] != '#' {
+		return color.RGBA{}, errors.New("strip color must be #rrggbb")
+	}
+	if _, err := fmt.Sscanf(s[1:], "%02x%02x%02x", &r, &g, &b); err != nil {
+		return color.RGBA{}, errors.New("strip color must be #rrggbb")
+	}
+	return color.RGBA{r, g, b, 0xff}, nil
+}
@@ -3,13 +3,13 @@ package storage
 import (
 	"log"
 	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/password"
 
 	"github.com/google/uuid"
-	"golang.org/x/crypto/bcrypt"
 	"gorm.io/gorm"
 )
 
-func SeedSuperUser(db *gorm.DB) {
+func SeedSuperUser(db *gorm.DB, hasher password.Hasher) {
 	var count int64
 	// Check if admin exists
 	db.Model(&models.User{}).Where("is_admin = ?", true).Count(&count)
@@ -17,13 +17,17 @@ func SeedSuperUser(db *gorm.DB) {
 		return // Admin already exists
 	}
 
-	hashed, _ := bcrypt.GenerateFromPassword([]byte("Admin123"), bcrypt.DefaultCost)
+	hashed, err := hasher.Hash("Admin123")
+	if err != nil {
+		log.Printf("Failed to seed superuser: %v", err)
+		return
+	}
 
 	admin := models.User{
 		ID:       uuid.New().String(),
 		Username: "superuser",
 		Email:    "wuby@superuser.com",
-		Password: string(hashed),
+		Password: hashed,
 		IsAdmin:  true,
 	}
 
@@ -0,0 +1,81 @@
+package tokens
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/hex"
+	"strings"
+)
+
+// Generate returns a URL-safe random token with 256 bits of entropy.
+func Generate() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+// Hash returns the hex-encoded SHA-256 digest of a token. Only the digest is
+// ever persisted, so a database leak does not expose usable tokens.
+func Hash(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+// Matches reports whether token hashes to the stored digest, in constant time.
+func Matches(token, hash string) bool {
+	if token == "" || hash == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(Hash(token)), []byte(hash)) == 1
+}
+
+const base62 = "0123456789ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz"
+
+// Slug returns a random base62 string of the given length, for use in
+// public URLs.
+func Slug(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	// 256 % 62 != 0, so reject bytes that would bias the distribution
+	out := make([]byte, 0, length)
+	for len(out) < length {
+		for _, v := range b {
+			if v < 248 && len(out) < length {
+				out = append(out, base62[int(v)%62])
+			}
+		}
+		if _, err := rand.Read(b); err != nil {
+			return "", err
+		}
+	}
+	return string(out), nil
+}
+
+// crockford is Crockford's base32 alphabet: no I, L, O or U, so codes read
+// off paper aren't ambiguous.
+const crockford = "0123456789ABCDEFGHJKMNPQRSTVWXYZ"
+
+// ShortCode returns a random Crockford base32 code of the given length.
+func ShortCode(length int) (string, error) {
+	b := make([]byte, length)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	for i, v := range b {
+		b[i] = crockford[v%32] // 256 is a multiple of 32, so no bias
+	}
+	return string(b), nil
+}
+
+// NormalizeShortCode maps typed input onto the canonical code: uppercase,
+// hyphens and spaces dropped, and the look-alikes I/L and O read as 1 and 0.
+func NormalizeShortCode(code string) string {
+	return strings.NewReplacer("-", "", " ", "", "I", "1", "L", "1", "O", "0").
+		Replace(strings.ToUpper(strings.TrimSpace(code)))
+}
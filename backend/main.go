@@ -8,7 +8,10 @@ import (
 	"github.com/gin-gonic/gin"
 	"web-photobooth/backend/internal/config"
 	"web-photobooth/backend/internal/handlers"
+	"web-photobooth/backend/internal/mailer"
+	"web-photobooth/backend/internal/middleware"
 	"web-photobooth/backend/internal/models"
+	"web-photobooth/backend/internal/password"
 	"web-photobooth/backend/internal/storage"
 )
 
@@ -16,6 +19,11 @@ func main() {
 	// 1. Load Configuration
 	cfg := config.LoadConfig()
 
+	hasher, err := password.NewHasher(cfg)
+	if err != nil {
+		log.Fatalf("Invalid password hashing config: %v", err)
+	}
+
 	// 2. Initialize Database
 	db, err := storage.InitDB(cfg)
 	if err != nil {
@@ -25,7 +33,7 @@ func main() {
 		if err := models.Migrate(db); err != nil {
 			log.Printf("Warning: Migration failed: %v", err)
 		}
-		storage.SeedSuperUser(db)
+		storage.SeedSuperUser(db, hasher)
 	}
 
 	// 3. Initialize S3
@@ -37,20 +45,32 @@ func main() {
 	}
 
 	// 4. Initialize Handler (Monolithic, no Supabase)
-	h := handlers.NewHandler(db, s3Client, cfg.DOBucket, cfg.JWTSecret, cfg.GuestExpirationDays)
+	h := handlers.NewHandler(db, s3Client, mailer.New(cfg), hasher, cfg)
 
 	// 5. Setup Router
 	r := gin.Default()
+	if err := r.SetTrustedProxies(cfg.TrustedProxies); err != nil {
+		log.Fatalf("Invalid TRUSTED_PROXIES: %v", err)
+	}
 
 	// 6. Configure CORS
-	r.Use(cors.New(cors.Config{
-		AllowAllOrigins:  true,
-		AllowMethods:     []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Content-Type", "Accept", "Authorization"},
-		ExposeHeaders:    []string{"Content-Length"},
-		AllowCredentials: true,
-		MaxAge:           12 * time.Hour,
-	}))
+	corsConfig := cors.Config{
+		AllowMethods:  []string{"GET", "POST", "PUT", "PATCH", "DELETE", "OPTIONS"},
+		AllowHeaders:  []string{"Origin", "Content-Type", "Accept", "Authorization", middleware.CSRFHeader, handlers.ManageTokenHeader, handlers.IdempotencyKeyHeader, handlers.ShareLinkPasswordHeader},
+		ExposeHeaders: []string{"Content-Length", "Idempotent-Replayed"},
+		MaxAge:        12 * time.Hour,
+	}
+	if len(cfg.CORSAllowedOrigins) > 0 {
+		// Credentials (cookies) are only allowed for an explicit origin list
+		corsConfig.AllowOrigins = cfg.CORSAllowedOrigins
+		corsConfig.AllowCredentials = true
+	} else {
+		if cfg.CookieAuth {
+			log.Println("Warning: AUTH_COOKIE_MODE is on but CORS_ALLOWED_ORIGINS is empty; cross-origin cookie requests will be rejected")
+		}
+		corsConfig.AllowAllOrigins = true
+	}
+	r.Use(cors.New(corsConfig))
 
 	// 7. API Routes (Now modularly registered)
 	h.RegisterRoutes(r)
@@ -59,10 +79,17 @@ func main() {
 	go func() {
 		// Run once on startup
 		h.CleanupExpiredStrips()
-		
+		h.CleanupMagicLinks()
+		h.CleanupIdempotencyKeys()
+		h.PurgeTrash()
+		h.BackfillShortCodes()
+
 		ticker := time.NewTicker(1 * time.Hour)
 		for range ticker.C {
 			h.CleanupExpiredStrips()
+			h.CleanupMagicLinks()
+			h.CleanupIdempotencyKeys()
+			h.PurgeTrash()
 		}
 	}()
 
@@ -0,0 +1,90 @@
+/**
+ * Session helpers shared by every page that talks to authenticated endpoints.
+ *
+ * The backend runs in one of two modes:
+ *  - bearer (default): login returns `access_token`, sent as Authorization.
+ *  - cookie (AUTH_COOKIE_MODE): login sets an HttpOnly session cookie and
+ *    returns `csrf_token`, which must be echoed as X-CSRF-Token on every
+ *    state-changing request.
+ *
+ * `sb_token` stays the "signed in" marker either way; in cookie mode it holds
+ * COOKIE_SESSION instead of a JWT.
+ */
+import { getApiUrl } from '$lib/config';
+
+const COOKIE_SESSION = 'cookie';
+
+interface SessionResponse {
+    access_token?: string;
+    csrf_token?: string;
+    user?: { id?: string; username?: string; is_admin?: boolean };
+}
+
+/** Stores a login/redeem response. Returns false if it carried no session. */
+export function storeSession(data: SessionResponse): boolean {
+    if (data.access_token) {
+        localStorage.setItem('sb_token', data.access_token);
+        localStorage.removeItem('sb_csrf');
+    } else if (data.csrf_token) {
+        localStorage.setItem('sb_token', COOKIE_SESSION);
+        localStorage.setItem('sb_csrf', data.csrf_token);
+    } else {
+        return false;
+    }
+
+    if (data.user) {
+        if (data.user.username) localStorage.setItem('sb_user', data.user.username);
+        if (data.user.id) localStorage.setItem('sb_uid', data.user.id);
+        localStorage.setItem('sb_admin', data.user.is_admin ? '1' : '0');
+    }
+    return true;
+}
+
+export function isSignedIn(): boolean {
+    return !!localStorage.getItem('sb_token');
+}
+
+export function isAdminSession(): boolean {
+    const token = localStorage.getItem('sb_token');
+    if (!token) return false;
+    if (token !== COOKIE_SESSION) {
+        try {
+            return JSON.parse(atob(token.split('.')[1])).is_admin === true;
+        } catch {
+            return false;
+        }
+    }
+    return localStorage.getItem('sb_admin') === '1';
+}
+
+/** Headers that authenticate a request in the current mode. */
+export function authHeaders(): Record<string, string> {
+    const token = localStorage.getItem('sb_token');
+    if (!token) return {};
+    if (token === COOKIE_SESSION) {
+        const csrf = localStorage.getItem('sb_csrf');
+        return csrf ? { 'X-CSRF-Token': csrf } : {};
+    }
+    return { Authorization: `Bearer ${token}` };
+}
+
+/** fetch with the session attached, in either mode. */
+export function authFetch(input: string, init: RequestInit = {}): Promise<Response> {
+    return fetch(input, {
+        ...init,
+        credentials: 'include',
+        headers: { ...authHeaders(), ...(init.headers as Record<string, string> | undefined) }
+    });
+}
+
+/** Forgets the session locally and expires the cookies, if any. */
+export function signOut(): void {
+    const cookieMode = localStorage.getItem('sb_token') === COOKIE_SESSION;
+    const headers = authHeaders();
+    for (const key of ['sb_token', 'sb_csrf', 'sb_user', 'sb_uid', 'sb_admin']) {
+        localStorage.removeItem(key);
+    }
+    if (cookieMode) {
+        fetch(getApiUrl('LOGOUT'), { method: 'POST', credentials: 'include', headers }).catch(() => {});
+    }
+}
@@ -13,11 +13,16 @@ export const API_CONFIG = {
     ENDPOINTS: {
         LOGIN: '/api/auth/login',
         SIGNUP: '/api/auth/signup',
+        MAGIC_LINK: '/api/auth/magic-link',
+        MAGIC_LINK_REDEEM: '/api/auth/magic-link/redeem',
+        LOGOUT: '/api/auth/logout',
         SAVE_STRIP: '/api/strips/save',
         GUEST_SAVE: '/api/strips/guest-save',
         PUBLIC_STRIP: '/api/strips/public/', // + id
         GET_STRIPS: '/api/strips/my-strips',
         STRIP_DETAIL: '/api/strips/', // + id
+        CLAIM_STRIP: '/api/strips/', // + id + /claim
+        GUEST_MANAGE: '/api/strips/guest/', // + id
         SYNC_USER: '/api/auth/sync',
         ADMIN_USERS: '/api/admin/users',
         ADMIN_STRIPS: '/api/admin/strips',
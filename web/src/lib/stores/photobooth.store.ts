@@ -4,6 +4,9 @@ const initial = {
   shots: [],
   finalStrip: null,
   uploadedId: null,
+  shareCode: null,
+  claimToken: null,
+  manageUrl: null,
   settings: null
 };
 
@@ -22,6 +25,9 @@ export const photoboothStore = writable<{
   shots: string[];
   finalStrip: string | null;
   uploadedId: string | null;
+  shareCode: string | null;
+  claimToken: string | null;
+  manageUrl: string | null;
   settings: {
     filter: string;
     stripColor: string;